@@ -0,0 +1,157 @@
+// Package optimize simplifies the syntax trees of simple programs before
+// they're run, without changing what they do
+package optimize
+
+import "simple/simpl"
+
+// Lines optimizes the lines of a program, returning the optimized lines. The
+// trees in `lines` aren't changed.
+//
+// Operators with constant operands are worked out, numbers are parsed ahead
+// of time, string literals added together are joined, and `if` and `while`
+// lines whose condition is always false are removed. Lines are only removed
+// from the top level of the program if it doesn't goto any line numbers,
+// since removing them would change which line is which, and the last line is
+// always kept, since the program ends with its value.
+func Lines(lines []*simpl.Node) []*simpl.Node {
+	keep := false
+	for _, line := range lines {
+		keep = keep || gotoesNumber(line)
+	}
+	optimized := make([]*simpl.Node, 0, len(lines))
+	for i, line := range lines {
+		n := fold(line)
+		if dead(n) && !keep && i < len(lines)-1 {
+			continue
+		}
+		optimized = append(optimized, n)
+	}
+	return optimized
+}
+
+// line optimizes a line of a block, or an operand, returning nil for lines
+// that never do anything
+func line(n *simpl.Node) *simpl.Node {
+	n = fold(n)
+	if dead(n) {
+		return nil
+	}
+	return n
+}
+
+// fold optimizes `n` and everything in it
+func fold(n *simpl.Node) *simpl.Node {
+	return simplify(n.Map(line))
+}
+
+// simplify optimizes `n`, whose operands have already been optimized
+func simplify(n *simpl.Node) *simpl.Node {
+	t := n.Token()
+	switch t.Class {
+	case simpl.Num:
+		// parse it now, rather than every time it's run
+		if v, ok := n.Constant(); ok {
+			return simpl.Literal(v, t.Pos)
+		}
+	case simpl.Unary:
+		if v, ok := n.Right().Constant(); ok {
+			return operate(n, v)
+		}
+	case simpl.Operator, simpl.Boolop:
+		left, lok := n.Left().Constant()
+		right, rok := n.Right().Constant()
+		switch {
+		case lok && rok:
+			return operate(n, left, right)
+		case lok && (t.Repr == "&" && !left.Bool() || t.Repr == "|" && left.Bool()):
+			// the right side is never run
+			return simpl.Literal(simpl.BoolValue(left.Bool()), t.Pos)
+		case t.Repr == "+":
+			return join(n)
+		}
+	}
+	return n
+}
+
+// operate works out the operator `n` with the constant `operands`. If that
+// fails, `n` is left for the error to happen when it's run.
+func operate(n *simpl.Node, operands ...simpl.Value) *simpl.Node {
+	v, err := simpl.Operate(n.Token().Repr, operands...)
+	if err != nil {
+		return n
+	}
+	return simpl.Literal(v, n.Token().Pos)
+}
+
+// join joins string literals on either side of the `+` operator `n`.
+// Adding anything to a string joins them, so when `a` is a string,
+// `"s" + (a + b)` is `("s" + a) + b`, and `(b + a) + "s"` is `b + (a + "s")`,
+// which brings string literals next to each other.
+func join(n *simpl.Node) *simpl.Node {
+	left, right := n.Left(), n.Right()
+	if isString(left) && isPlus(right) && joins(right.Left()) {
+		return simplify(n.WithOperands(simplify(right.WithOperands(left, right.Left())), right.Right()))
+	}
+	if isString(right) && isPlus(left) && joins(left.Right()) {
+		return simplify(n.WithOperands(left.Left(), simplify(left.WithOperands(left.Right(), right))))
+	}
+	return n
+}
+
+// isString returns whether `n` is a string literal
+func isString(n *simpl.Node) bool {
+	return n.Token().Class == simpl.Str
+}
+
+// joins returns whether `n` is always a string: a string literal, or a
+// string added to something
+func joins(n *simpl.Node) bool {
+	return isString(n) || isPlus(n) && (joins(n.Left()) || joins(n.Right()))
+}
+
+// isPlus returns whether `n` is the `+` operator
+func isPlus(n *simpl.Node) bool {
+	return n.Token().Class == simpl.Operator && n.Token().Repr == "+"
+}
+
+// dead returns whether the line `n` is an `if` or `while` whose condition is
+// always false, with no `else`
+func dead(n *simpl.Node) bool {
+	t := n.Token()
+	if t.Class != simpl.Keyword {
+		return false
+	}
+	var cond *simpl.Node
+	switch {
+	case t.Repr == "if" && len(n.Alt()) == 0:
+		cond = n.Left()
+	case t.Repr == "while":
+		cond = n.Right()
+	default:
+		return false
+	}
+	v, ok := cond.Constant()
+	return ok && !v.Bool()
+}
+
+// gotoesNumber returns whether `n` has a goto to a line number in it
+func gotoesNumber(n *simpl.Node) bool {
+	if n == nil {
+		return false
+	}
+	t := n.Token()
+	if t.Class == simpl.Builtin && t.Repr == "goto" && n.Right() != nil && n.Right().Token().Class != simpl.Var {
+		return true
+	}
+	if gotoesNumber(n.Left()) || gotoesNumber(n.Right()) {
+		return true
+	}
+	for _, lines := range [][]*simpl.Node{n.Body(), n.Alt(), n.Args()} {
+		for _, line := range lines {
+			if gotoesNumber(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
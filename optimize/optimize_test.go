@@ -0,0 +1,174 @@
+package optimize
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"simple/simpl"
+)
+
+func TestFolds(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected simpl.Value
+	}{
+		{input: "2 * 3 + 1", expected: simpl.IntValue(7)},
+		{input: "7 / 2", expected: simpl.FloatValue(3.5)},
+		{input: "1.5e1", expected: simpl.FloatValue(15)},
+		{input: "-(4 - 6)", expected: simpl.IntValue(2)},
+		{input: "!0", expected: simpl.BoolValue(true)},
+		{input: "( 1 < 2 ) & ( \"a\" == \"a\" )", expected: simpl.BoolValue(true)},
+		{input: "false & f()", expected: simpl.BoolValue(false)},
+		{input: "1 | f()", expected: simpl.BoolValue(true)},
+		{input: "\"a\" + 1 + 2", expected: simpl.StringValue("a3")},
+		{input: "\"a\" + (\"b\" + \"c\")", expected: simpl.StringValue("abc")},
+	}
+	for _, test := range cases {
+		t.Run(test.input, func(t *testing.T) {
+			lines := Lines(parse(t, test.input))
+			v, ok := lines[0].Constant()
+			if !ok {
+				t.Fatalf("expected a constant, got %v", lines[0].Token())
+			}
+			if v != test.expected {
+				t.Errorf("expected %v %v got %v %v", test.expected.Kind, test.expected, v.Kind, v)
+			}
+		})
+	}
+}
+
+func TestLeavesUnfolded(t *testing.T) {
+	cases := []string{
+		"x + 1",
+		"5 % 0",
+		"1 - \"a\"",
+		"true & f()",
+		"[1] < [2]",
+	}
+	for _, input := range cases {
+		t.Run(input, func(t *testing.T) {
+			lines := Lines(parse(t, input))
+			if _, ok := lines[0].Constant(); ok {
+				t.Errorf("expected it not to be folded, got %v", lines[0].Token())
+			}
+		})
+	}
+}
+
+func TestJoinsStrings(t *testing.T) {
+	cases := []struct {
+		input    string
+		left     string
+		right    string
+		constant bool // whether the right side is the constant
+	}{
+		{input: "\"a\" + (\"b\" + x)", left: "ab", right: "x"},
+		{input: "(x + \"a\") + \"b\"", left: "x", right: "ab", constant: true},
+		{input: "\"a\" + ((\"b\" + x) + (\"c\" + 1))", left: "+", right: "c1", constant: true},
+	}
+	for _, test := range cases {
+		t.Run(test.input, func(t *testing.T) {
+			n := Lines(parse(t, test.input))[0]
+			if n.Token().Repr != "+" {
+				t.Fatalf("expected '+', got %v", n.Token())
+			}
+			if left := n.Left().Token().Repr; left != test.left {
+				t.Errorf("expected %v on the left, got %v", test.left, left)
+			}
+			if right := n.Right().Token().Repr; right != test.right {
+				t.Errorf("expected %v on the right, got %v", test.right, right)
+			}
+			if _, ok := n.Right().Constant(); ok != test.constant {
+				t.Errorf("expected the right side to be constant: %v", test.constant)
+			}
+		})
+	}
+}
+
+func TestRemovesDeadLines(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected int
+	}{
+		{input: "if 0 print 1\nprint 2", expected: 1},
+		{input: "print 1\nif 0 print 2", expected: 2},
+		{input: "if 1 - 1\nprint 1\nend\nwhile \"\"\nprint 2\nend\nprint 3", expected: 1},
+		{input: "if 0\nprint 1\nelse\nprint 2\nend", expected: 1},
+		{input: "if 1 print 1", expected: 1},
+		{input: "if 0 print 1\ngoto 1 + 1", expected: 2},
+		{input: "l: print 1\nif 0 print 1\ngoto l", expected: 2},
+	}
+	for _, test := range cases {
+		t.Run(test.input, func(t *testing.T) {
+			if lines := Lines(parse(t, test.input)); len(lines) != test.expected {
+				t.Errorf("expected %v lines, got %v", test.expected, len(lines))
+			}
+		})
+	}
+
+	lines := Lines(parse(t, "func f()\nif false print 1\nreturn 2\nend"))
+	if body := lines[0].Body(); len(body) != 1 {
+		t.Errorf("expected 1 line in the function, got %v", len(body))
+	}
+}
+
+// TestSameOutput checks that programs do the same thing after being optimized
+func TestSameOutput(t *testing.T) {
+	programs := map[string]string{
+		"goto a number":  "i = 0\nif 0 print \"never\"\ni = i + 1\nprint i\nif i < 3 goto 1 + 2",
+		"errors":         "print \"a\" + 1\nprint 5 % 0",
+		"strings":        "x = 1\nprint \"a\" + (\"b\" + x) + \"c\" + 1.5 * 2",
+		"dead last line": "not f\nif 0 print 1",
+		"dead lines":     "if 0 print 1\n1 + 2\nwhile false\nend\n3 * 4",
+	}
+	examples, err := filepath.Glob("../example/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, example := range examples {
+		b, err := os.ReadFile(example)
+		if err != nil {
+			t.Fatal(err)
+		}
+		programs[example] = string(b)
+	}
+	for name, input := range programs {
+		t.Run(name, func(t *testing.T) {
+			lines := parse(t, input)
+			expected := run(lines)
+			if got := run(Lines(lines)); got != expected {
+				t.Errorf("expected %q got %q", expected, got)
+			}
+		})
+	}
+}
+
+// parse parses `input`
+func parse(t *testing.T, input string) []*simpl.Node {
+	t.Helper()
+	l := simpl.Lexer{In: strings.NewReader(input)}
+	tkns, errs := l.Lex()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	p := simpl.Parser{Tokens: tkns}
+	if errs := p.Parse(); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	return p.Lines
+}
+
+// run runs `lines`, returning what they printed, and the value or error they
+// ended with
+func run(lines []*simpl.Node) string {
+	out := strings.Builder{}
+	i := simpl.NewInterpreter(&lines, &out)
+	val, err := i.Interpret()
+	if err != nil {
+		out.WriteString(err.Error())
+	}
+	return fmt.Sprintf("%v\n%v %v", out.String(), val.Kind, val)
+}
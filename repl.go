@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"simple/simpl"
+)
+
+const (
+	prompt         = "> "
+	continuePrompt = ". "
+)
+
+// repl reads lines from `r`, runs them with `i`, and writes the value of each
+// expression to `w`. Lines are appended to the program held by `p`, so line
+// numbers (and therefore `goto`) keep counting from the earlier lines. Errors
+// give their positions in `<stdin>`, counting every line read.
+//
+// It returns the exit code the program gave to exit(), or 0 at the end of `r`.
+//
+// A line ending in a backslash, or one with unclosed parentheses, brackets,
+// or braces, is joined with the line after it before being run. Blocks are
+// run once they're closed with `end`.
+func repl(p *simpl.Parser, i *simpl.Interpreter, r io.Reader, w io.Writer) int {
+	p.Incremental = true
+	// the program can read from `r` too, so only read one line at a time
+	reader := bufio.NewReader(r)
+	src := ""
+	read := 0  // how many lines have been read
+	first := 1 // the line `src` starts on
+	fmt.Fprint(w, prompt)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			break
+		}
+		read++
+		if src == "" {
+			first = read
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasSuffix(line, "\\") {
+			src += strings.TrimSuffix(line, "\\") + " "
+			fmt.Fprint(w, continuePrompt)
+			continue
+		}
+		src += line
+
+		l := simpl.Lexer{In: strings.NewReader(src), File: "<stdin>", Line: first}
+		tokens, errors := l.Lex()
+		if len(errors) == 0 && openParens(tokens) > 0 {
+			src += " "
+			fmt.Fprint(w, continuePrompt)
+			continue
+		}
+		src = ""
+
+		if len(errors) == 0 {
+			p.Tokens = tokens
+			errors = p.Parse()
+		}
+		for _, err := range errors {
+			fmt.Fprintln(w, "ERROR:", err)
+		}
+		if p.InBlock() {
+			fmt.Fprint(w, continuePrompt)
+			continue
+		}
+		if len(errors) == 0 {
+			res, err := i.Interpret()
+			if exit, ok := err.(*simpl.ExitError); ok {
+				return exit.Code
+			}
+			if err != nil {
+				fmt.Fprintln(w, "ERROR:", err)
+			} else if res.Kind != simpl.Nil {
+				fmt.Fprintln(w, res)
+			}
+		}
+		fmt.Fprint(w, prompt)
+	}
+	fmt.Fprintln(w)
+	return 0
+}
+
+// openParens returns how many parentheses, brackets, and braces in `tokens`
+// haven't been closed
+func openParens(tokens []simpl.Token) int {
+	open := 0
+	for _, t := range tokens {
+		if t.Class != simpl.Paren && t.Class != simpl.Bracket && t.Class != simpl.Brace {
+			continue
+		}
+		switch t.Repr {
+		case "(", "[", "{":
+			open++
+		case ")", "]", "}":
+			open--
+		}
+	}
+	return open
+}
@@ -0,0 +1,153 @@
+package simpl
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// builtin is a function that can be called without being defined first
+type builtin struct {
+	min, max int // how many arguments it takes, with no limit if max is -1
+	fn       func(in *Interpreter, args []Value) (Value, error)
+}
+
+// builtins holds the builtin functions by name
+var builtins = map[string]builtin{
+	// lists and maps
+	"len":    {1, 1, builtinLen},
+	"append": {2, 2, builtinAppend},
+	"pop":    {1, 1, builtinPop},
+	"keys":   {1, 1, builtinKeys},
+	"has":    {2, 2, builtinHas},
+	"delete": {2, 2, builtinDelete},
+
+	// strings, which are in strings.go
+	"upper":    {1, 1, builtinUpper},
+	"lower":    {1, 1, builtinLower},
+	"substr":   {2, 3, builtinSubstr},
+	"split":    {2, 2, builtinSplit},
+	"join":     {2, 2, builtinJoin},
+	"trim":     {1, 1, builtinTrim},
+	"replace":  {3, 3, builtinReplace},
+	"contains": {2, 2, builtinContains},
+	"index":    {2, 2, builtinIndex},
+	"format":   {1, -1, builtinFormat},
+
+	// math, which is in math.go
+	"abs":    {1, 1, builtinAbs},
+	"floor":  {1, 1, builtinFloor},
+	"round":  {1, 1, builtinRound},
+	"sqrt":   {1, 1, builtinSqrt},
+	"pow":    {2, 2, builtinPow},
+	"min":    {1, -1, builtinMin},
+	"max":    {1, -1, builtinMax},
+	"random": {0, 2, builtinRandom},
+
+	// input, which is in input.go
+	"input":    {0, 1, builtinInput},
+	"readline": {0, 0, builtinReadline},
+	"int":      {1, 1, builtinInt},
+	"float":    {1, 1, builtinFloat},
+
+	// files, which are in files.go
+	"readfile":   {1, 1, builtinReadfile},
+	"writefile":  {2, 2, builtinWritefile},
+	"appendfile": {2, 2, builtinAppendfile},
+	"exists":     {1, 1, builtinExists},
+
+	// the process running the program, which is in process.go
+	"env":  {1, 1, builtinEnv},
+	"exit": {0, 1, builtinExit},
+}
+
+// arity checks that `b` can be called with `n` arguments
+func (b builtin) arity(n int) error {
+	switch {
+	case b.min <= n && (n <= b.max || b.max == -1):
+		return nil
+	case b.min == b.max:
+		return fmt.Errorf("takes %v arguments, got %v", b.min, n)
+	case b.max == -1:
+		return fmt.Errorf("takes at least %v arguments, got %v", b.min, n)
+	}
+	return fmt.Errorf("takes %v to %v arguments, got %v", b.min, b.max, n)
+}
+
+// builtinLen returns the length of a list, how many entries a map has, or
+// how many characters a string has
+func builtinLen(in *Interpreter, args []Value) (Value, error) {
+	switch args[0].Kind {
+	case String:
+		return IntValue(int64(utf8.RuneCountInString(args[0].s))), nil
+	case List:
+		return IntValue(int64(len(*args[0].l))), nil
+	case Map:
+		return IntValue(int64(len(*args[0].m))), nil
+	}
+	return Value{}, fmt.Errorf("expected a list, map, or string, got %v '%v'", args[0].Kind, args[0])
+}
+
+// builtinAppend adds an element to the end of a list, and returns the list
+func builtinAppend(in *Interpreter, args []Value) (Value, error) {
+	elems, err := args[0].list()
+	if err != nil {
+		return Value{}, err
+	}
+	*elems = append(*elems, args[1])
+	return args[0], nil
+}
+
+// builtinPop removes the last element of a list and returns it
+func builtinPop(in *Interpreter, args []Value) (Value, error) {
+	elems, err := args[0].list()
+	if err != nil {
+		return Value{}, err
+	}
+	if len(*elems) == 0 {
+		return Value{}, fmt.Errorf("can't pop from an empty list")
+	}
+	last := (*elems)[len(*elems)-1]
+	*elems = (*elems)[:len(*elems)-1]
+	return last, nil
+}
+
+// builtinKeys returns a list of the keys of a map, in sorted order
+func builtinKeys(in *Interpreter, args []Value) (Value, error) {
+	if _, err := args[0].mapping(); err != nil {
+		return Value{}, err
+	}
+	keys := args[0].keys()
+	elems := make([]Value, len(keys))
+	for i, k := range keys {
+		elems[i] = StringValue(k)
+	}
+	return ListValue(elems), nil
+}
+
+// builtinHas returns whether a map has a key
+func builtinHas(in *Interpreter, args []Value) (Value, error) {
+	entries, err := args[0].mapping()
+	if err != nil {
+		return Value{}, err
+	}
+	k, err := key(args[1])
+	if err != nil {
+		return Value{}, err
+	}
+	_, ok := (*entries)[k]
+	return BoolValue(ok), nil
+}
+
+// builtinDelete removes a key from a map, if it's there
+func builtinDelete(in *Interpreter, args []Value) (Value, error) {
+	entries, err := args[0].mapping()
+	if err != nil {
+		return Value{}, err
+	}
+	k, err := key(args[1])
+	if err != nil {
+		return Value{}, err
+	}
+	delete(*entries, k)
+	return Value{}, nil
+}
@@ -0,0 +1,449 @@
+package simpl
+
+import "fmt"
+
+// opcode is the operation of an instruction
+type opcode uint8
+
+// The operations of the bytecode. Each one works on the stack of values, and
+// some of them take an argument, which is described next to them.
+const (
+	opConst       opcode = iota // push constant `a`
+	opNil                       // push nil
+	opPop                       // drop the top of the stack
+	opResult                    // pop the value of a line of the program
+	opGlobal                    // push the global variable named by name `a`
+	opSetGlobal                 // pop into the global variable named by name `a`
+	opLocal                     // push local variable `a`, or the global one if it isn't set
+	opSetLocal                  // pop into local variable `a`
+	opArith                     // apply arithmetic operator `a` to the top two values
+	opCompare                   // apply comparison `a` to the top two values
+	opBool                      // replace the top of the stack with whether it's true
+	opNot                       // replace the top of the stack with whether it's false
+	opNegate                    // flip the sign of the top of the stack
+	opJump                      // jump to instruction `a`
+	opJumpIfFalse               // pop, and jump to instruction `a` if it's false
+	opJumpIfTrue                // pop, and jump to instruction `a` if it's true
+	opList                      // pop `a` values into a list
+	opKey                       // check that the top of the stack can be a map key
+	opMap                       // pop `a` keys and values into a map
+	opIndex                     // pop an index and a list or map, and push the element
+	opSetIndex                  // pop an index, a list or map, and a value to set there
+	opPrint                     // pop and print
+	opGotoLabel                 // jump to the start of the line of the label named by name `a`
+	opGoto                      // pop a line number, and jump to the start of that line
+	opForNumber                 // check that the top of the stack is a number for a for loop
+	opForPrep                   // pop the end and start of a for loop into temporaries `a` and `a+1`
+	opForNext                   // push the counter in temporary `a` and skip an instruction, unless it's past the end
+	opForStep                   // count up the counter in temporary `a`, or make it nil once it's reached the end
+	opCheckCall                 // check that call `a` can be made, before its arguments are pushed
+	opCall                      // make call `a` with its arguments on the stack
+	opReturn                    // return from a function with the value on top of the stack
+	opFail                      // stop with error `a`
+)
+
+// instr is an instruction of the bytecode
+type instr struct {
+	op opcode
+	a  int32
+}
+
+// arithOps and compareOps are the operators picked out by the argument of
+// opArith and opCompare
+var (
+	arithOps   = []string{"+", "-", "*", "/", "%"}
+	compareOps = []string{"==", "!=", "<", ">", "<=", ">="}
+)
+
+// chunk is a piece of compiled code: the lines of the program, or the body of
+// a function
+type chunk struct {
+	code  []instr
+	pos   []Pos // the position in the source of each instruction, for errors
+	temps int   // how many temporaries its for loops need
+}
+
+// function is a compiled function definition
+type function struct {
+	chunk
+	params int
+	locals []string // the names of its local variables, starting with its parameters
+}
+
+// callSite is a call in the program, with the name it calls and how many
+// arguments it passes
+type callSite struct {
+	name string
+	args int
+}
+
+// program is the compiled form of the lines run so far, which grows as more
+// lines are interpreted
+type program struct {
+	main   chunk
+	lines  []int    // the index in main.code of the start of each line
+	consts []Value  // the constants used by opConst
+	names  []string // the names of global variables and labels
+	calls  []callSite
+	errs   []error // the errors used by opFail
+	funcs  map[*Node]*function
+	named  map[string]int // index in `names` of each name
+
+	constants map[Value]int // index in `consts` of each constant
+}
+
+// compiler compiles a function, or lines of the program
+type compiler struct {
+	prog   *program
+	c      *chunk
+	fn     *function      // nil for the lines of the program
+	locals map[string]int // index of each local variable of `fn`
+}
+
+// compileLines compiles the lines of the program starting at `start`, which
+// are appended to the code of the lines before them
+func (p *program) compileLines(lines []*Node, start int) {
+	c := compiler{prog: p, c: &p.main}
+	for _, line := range lines[start:] {
+		p.lines = append(p.lines, len(p.main.code))
+		c.expr(line)
+		c.emit(opResult, 0, line)
+	}
+}
+
+// function returns the compiled function for the definition `def`, compiling
+// it the first time it's called
+func (p *program) function(def *Node) *function {
+	if fn, ok := p.funcs[def]; ok {
+		return fn
+	}
+	fn := &function{params: len(def.right.args)}
+	c := compiler{prog: p, c: &fn.chunk, fn: fn, locals: make(map[string]int)}
+	for _, param := range def.right.args {
+		c.local(param.val.Repr)
+	}
+	// every variable assigned in a function is local to it
+	c.assigns(def.body)
+	c.block(def.body)
+	c.emit(opNil, 0, def)
+	c.emit(opReturn, 0, def)
+	if p.funcs == nil {
+		p.funcs = make(map[*Node]*function)
+	}
+	p.funcs[def] = fn
+	return fn
+}
+
+// emit appends an instruction for the node `n`, returning its index
+func (c *compiler) emit(op opcode, a int, n *Node) int {
+	var pos Pos
+	if n != nil {
+		pos = n.Pos()
+	}
+	c.c.code = append(c.c.code, instr{op: op, a: int32(a)})
+	c.c.pos = append(c.c.pos, pos)
+	return len(c.c.code) - 1
+}
+
+// patch makes the jump at `at` jump to the next instruction emitted
+func (c *compiler) patch(at int) {
+	c.c.code[at].a = int32(len(c.c.code))
+}
+
+// fail emits an instruction that stops with an error at `n`
+func (c *compiler) fail(n *Node, format string, a ...interface{}) {
+	c.prog.errs = append(c.prog.errs, fmt.Errorf(format, a...))
+	c.emit(opFail, len(c.prog.errs)-1, n)
+}
+
+// constant returns the index of `v` in the constants
+func (c *compiler) constant(v Value) int {
+	if i, ok := c.prog.constants[v]; ok {
+		return i
+	}
+	if c.prog.constants == nil {
+		c.prog.constants = make(map[Value]int)
+	}
+	c.prog.consts = append(c.prog.consts, v)
+	c.prog.constants[v] = len(c.prog.consts) - 1
+	return len(c.prog.consts) - 1
+}
+
+// name returns the index of `name` in the names
+func (c *compiler) name(name string) int {
+	if i, ok := c.prog.named[name]; ok {
+		return i
+	}
+	if c.prog.named == nil {
+		c.prog.named = make(map[string]int)
+	}
+	c.prog.names = append(c.prog.names, name)
+	c.prog.named[name] = len(c.prog.names) - 1
+	return len(c.prog.names) - 1
+}
+
+// local returns the index of the local variable `name` of the function,
+// adding it if it's new
+func (c *compiler) local(name string) int {
+	if i, ok := c.locals[name]; ok {
+		return i
+	}
+	c.fn.locals = append(c.fn.locals, name)
+	c.locals[name] = len(c.fn.locals) - 1
+	return len(c.fn.locals) - 1
+}
+
+// assigns adds the variables assigned anywhere in `lines` to the local
+// variables of the function
+func (c *compiler) assigns(lines []*Node) {
+	for _, line := range lines {
+		switch {
+		case line.val.Class == Assignment && line.left != nil && line.left.val.Class == Var:
+			c.local(line.left.val.Repr)
+		case isKeyword(line, "for"):
+			c.local(line.right.left.val.Repr)
+		}
+		c.assigns(line.body)
+		c.assigns(line.alt)
+	}
+}
+
+// block compiles the lines of a block, dropping their values
+func (c *compiler) block(lines []*Node) {
+	for _, line := range lines {
+		c.expr(line)
+		c.emit(opPop, 0, line)
+	}
+}
+
+// load pushes the variable `name`
+func (c *compiler) load(n *Node) {
+	if i, ok := c.locals[n.val.Repr]; ok {
+		c.emit(opLocal, i, n)
+		return
+	}
+	c.emit(opGlobal, c.name(n.val.Repr), n)
+}
+
+// store pops into the variable `name`, which is local inside a function
+func (c *compiler) store(name string, n *Node) {
+	if c.fn != nil {
+		c.emit(opSetLocal, c.local(name), n)
+		return
+	}
+	c.emit(opSetGlobal, c.name(name), n)
+}
+
+// temps reserves `count` temporaries in the chunk, returning the first one
+func (c *compiler) temps(count int) int {
+	c.c.temps += count
+	return c.c.temps - count
+}
+
+// expr compiles the node `n`, which leaves its value on the stack. Nodes that
+// don't have values, like assignments, leave nil.
+func (c *compiler) expr(n *Node) {
+	if n == nil {
+		// a missing operand is nil, like it is when walking the trees
+		c.emit(opNil, 0, n)
+		return
+	}
+	switch n.val.Class {
+	case Var:
+		c.load(n)
+	case Call:
+		c.call(n)
+	case Str:
+		c.emit(opConst, c.constant(StringValue(n.val.Repr)), n)
+	case Num:
+		v, err := n.number()
+		if err != nil {
+			c.fail(n, "%v", err)
+			return
+		}
+		c.emit(opConst, c.constant(v), n)
+	case Boolean:
+		c.emit(opConst, c.constant(BoolValue(n.val.Repr == "true")), n)
+	case ListLit:
+		for _, arg := range n.args {
+			c.expr(arg)
+		}
+		c.emit(opList, len(n.args), n)
+	case MapLit:
+		for _, entry := range n.args {
+			c.expr(entry.left)
+			c.expr(entry.right)
+			c.emit(opKey, 0, entry.left)
+		}
+		c.emit(opMap, len(n.args), n)
+	case Index:
+		c.expr(n.left)
+		c.expr(n.right)
+		c.emit(opIndex, 0, n)
+	case Unary:
+		c.expr(n.right)
+		if n.val.Repr == "-" {
+			c.emit(opNegate, 0, n)
+		} else {
+			c.emit(opNot, 0, n)
+		}
+	case Operator, Boolop:
+		c.operator(n)
+	case Builtin:
+		c.builtin(n)
+	case Assignment:
+		if n.left == nil || n.left.val.Class != Var && n.left.val.Class != Index {
+			c.fail(n, "can only assign to a variable")
+			return
+		}
+		c.expr(n.right)
+		if n.left.val.Class == Index {
+			c.expr(n.left.left)
+			c.expr(n.left.right)
+			c.emit(opSetIndex, 0, n.left)
+		} else {
+			c.store(n.left.val.Repr, n.left)
+		}
+		c.emit(opNil, 0, n)
+	case Keyword:
+		c.keyword(n)
+	case Label:
+		if n.right != nil {
+			c.expr(n.right)
+		} else {
+			c.emit(opNil, 0, n)
+		}
+	default:
+		c.fail(n, "cannot evaluate node of type %v", n.val.Class)
+	}
+}
+
+// operator compiles an operator. The right side of `&` and `|` is skipped if
+// it doesn't make a difference.
+func (c *compiler) operator(n *Node) {
+	c.expr(n.left)
+	switch n.val.Repr {
+	case "&", "|":
+		op, short := opJumpIfFalse, false
+		if n.val.Repr == "|" {
+			op, short = opJumpIfTrue, true
+		}
+		skip := c.emit(op, 0, n)
+		c.expr(n.right)
+		c.emit(opBool, 0, n)
+		end := c.emit(opJump, 0, n)
+		c.patch(skip)
+		c.emit(opConst, c.constant(BoolValue(short)), n)
+		c.patch(end)
+		return
+	}
+	c.expr(n.right)
+	for i, op := range compareOps {
+		if op == n.val.Repr {
+			c.emit(opCompare, i, n)
+			return
+		}
+	}
+	for i, op := range arithOps {
+		if op == n.val.Repr {
+			c.emit(opArith, i, n)
+			return
+		}
+	}
+	c.fail(n, "unknown operator '%v'", n.val.Repr)
+}
+
+// builtin compiles print and goto
+func (c *compiler) builtin(n *Node) {
+	switch {
+	case n.val.Repr == "goto" && n.right.val.Class == Var:
+		// a name is always a label, anything else is a line number. Labels
+		// are looked up when the goto is run, since lines added later can
+		// define them.
+		c.emit(opGotoLabel, c.name(n.right.val.Repr), n.right)
+	case n.val.Repr == "goto":
+		c.expr(n.right)
+		c.emit(opGoto, 0, n)
+	default:
+		if n.right != nil {
+			c.expr(n.right)
+		} else {
+			c.emit(opNil, 0, n)
+		}
+		if n.val.Repr == "print" {
+			c.emit(opPrint, 0, n)
+		} else {
+			c.emit(opPop, 0, n)
+		}
+		c.emit(opNil, 0, n)
+	}
+}
+
+// keyword compiles if, while, for and return. Functions are compiled when
+// they're first called.
+func (c *compiler) keyword(n *Node) {
+	switch n.val.Repr {
+	case "if":
+		c.expr(n.left)
+		skip := c.emit(opJumpIfFalse, 0, n)
+		if n.right != nil {
+			c.expr(n.right)
+			c.emit(opPop, 0, n)
+			c.patch(skip)
+			break
+		}
+		c.block(n.body)
+		end := c.emit(opJump, 0, n)
+		c.patch(skip)
+		c.block(n.alt)
+		c.patch(end)
+	case "while":
+		start := len(c.c.code)
+		c.expr(n.right)
+		end := c.emit(opJumpIfFalse, 0, n)
+		c.block(n.body)
+		c.emit(opJump, start, n)
+		c.patch(end)
+	case "for":
+		variable := n.right.left
+		c.expr(n.right.right.left)
+		c.emit(opForNumber, 0, n)
+		c.expr(n.right.right.right)
+		c.emit(opForNumber, 0, n)
+		t := c.temps(2)
+		c.emit(opForPrep, t, n)
+		start := c.emit(opForNext, t, n)
+		end := c.emit(opJump, 0, n)
+		c.store(variable.val.Repr, variable)
+		c.block(n.body)
+		c.emit(opForStep, t, n)
+		c.emit(opJump, start, n)
+		c.patch(end)
+	case "func":
+		// functions are defined before anything is run
+	case "return":
+		if n.right != nil {
+			c.expr(n.right)
+		} else {
+			c.emit(opNil, 0, n)
+		}
+		c.emit(opReturn, 0, n)
+		return
+	default:
+		c.fail(n, "unexpected '%v'", n.val.Repr)
+		return
+	}
+	c.emit(opNil, 0, n)
+}
+
+// call compiles a call. Whether the call can be made is checked before its
+// arguments are run.
+func (c *compiler) call(n *Node) {
+	c.prog.calls = append(c.prog.calls, callSite{name: n.val.Repr, args: len(n.args)})
+	site := len(c.prog.calls) - 1
+	c.emit(opCheckCall, site, n)
+	for _, arg := range n.args {
+		c.expr(arg)
+	}
+	c.emit(opCall, site, n)
+}
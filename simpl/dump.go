@@ -0,0 +1,214 @@
+package simpl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// PrintTree writes the tree to `w`, one node per line, with each node's
+// operands, arguments and blocks indented under it
+func (n *Node) PrintTree(w io.Writer) error {
+	return n.printTree(w, "", "")
+}
+
+// printTree writes the tree to `w`, indented by `indent`, with `role`
+// saying what the node is to its parent
+func (n *Node) printTree(w io.Writer, indent, role string) error {
+	if _, err := fmt.Fprintf(w, "%v%v%v %q %v\n", indent, role, n.val.Class, n.val.Repr, lineCol(n.Pos())); err != nil {
+		return err
+	}
+	indent += "  "
+	if n.left != nil {
+		if err := n.left.printTree(w, indent, "left: "); err != nil {
+			return err
+		}
+	}
+	if n.right != nil {
+		if err := n.right.printTree(w, indent, "right: "); err != nil {
+			return err
+		}
+	}
+	for _, b := range n.blocks() {
+		if _, err := fmt.Fprintf(w, "%v%v:\n", indent, b.name); err != nil {
+			return err
+		}
+		for _, c := range b.nodes {
+			if err := c.printTree(w, indent+"  ", ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// block is a list of nodes under a node, with what they are to it
+type block struct {
+	name  string
+	nodes []*Node
+}
+
+// blocks returns the arguments and blocks of lines of the node that aren't
+// empty
+func (n *Node) blocks() []block {
+	var blocks []block
+	for _, b := range []block{{"args", n.args}, {"body", n.body}, {"else", n.alt}} {
+		if len(b.nodes) > 0 {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
+
+// lineCol returns the line and column of `pos`, leaving out the file so that
+// dumps of the same program are the same wherever it is
+func lineCol(pos Pos) string {
+	return fmt.Sprintf("%v:%v", pos.Line, pos.Col)
+}
+
+// DumpText writes the trees of `lines` to `w` with PrintTree
+func DumpText(w io.Writer, lines []*Node) error {
+	for _, line := range lines {
+		if err := line.PrintTree(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonNode is how a node is written as JSON. Its fields are always written
+// in the same order, and empty ones are left out, so that dumps can be
+// diffed.
+type jsonNode struct {
+	Class string      `json:"class"`
+	Repr  string      `json:"repr"`
+	Line  int         `json:"line"`
+	Col   int         `json:"col"`
+	Left  *jsonNode   `json:"left,omitempty"`
+	Right *jsonNode   `json:"right,omitempty"`
+	Args  []*jsonNode `json:"args,omitempty"`
+	Body  []*jsonNode `json:"body,omitempty"`
+	Else  []*jsonNode `json:"else,omitempty"`
+}
+
+// toJSON converts the tree `n` to jsonNodes
+func toJSON(n *Node) *jsonNode {
+	if n == nil {
+		return nil
+	}
+	return &jsonNode{
+		Class: n.val.Class.String(),
+		Repr:  n.val.Repr,
+		Line:  n.Pos().Line,
+		Col:   n.Pos().Col,
+		Left:  toJSON(n.left),
+		Right: toJSON(n.right),
+		Args:  linesToJSON(n.args),
+		Body:  linesToJSON(n.body),
+		Else:  linesToJSON(n.alt),
+	}
+}
+
+// linesToJSON converts each of `lines` to jsonNodes
+func linesToJSON(lines []*Node) []*jsonNode {
+	if len(lines) == 0 {
+		return nil
+	}
+	nodes := make([]*jsonNode, len(lines))
+	for i, line := range lines {
+		nodes[i] = toJSON(line)
+	}
+	return nodes
+}
+
+// DumpJSON writes the trees of `lines` to `w` as an indented JSON array,
+// with an object for each node
+func DumpJSON(w io.Writer, lines []*Node) error {
+	nodes := linesToJSON(lines)
+	if nodes == nil {
+		nodes = []*jsonNode{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(nodes)
+}
+
+// DumpDot writes the trees of `lines` to `w` as a Graphviz graph, with the
+// lines hanging off a node for the program
+func DumpDot(w io.Writer, lines []*Node) error {
+	d := dotWriter{}
+	d.printf("digraph ast {\n")
+	d.printf("\tnode [shape=box, fontname=monospace];\n")
+	d.printf("\tn0 [label=\"program\"];\n")
+	for i, line := range lines {
+		d.node(line, 0, fmt.Sprint(i+1))
+	}
+	d.printf("}\n")
+	_, err := io.WriteString(w, d.String())
+	return err
+}
+
+// dotWriter builds up a Graphviz graph
+type dotWriter struct {
+	strings.Builder
+	nodes int // how many nodes have been written, besides the program
+}
+
+func (d *dotWriter) printf(format string, a ...interface{}) {
+	fmt.Fprintf(d, format, a...)
+}
+
+// node writes the tree `n`, with an edge labelled `label` to it from the
+// node numbered `parent`
+func (d *dotWriter) node(n *Node, parent int, label string) {
+	d.nodes++
+	id := d.nodes
+	d.printf("\tn%v [label=%q];\n", id, fmt.Sprintf("%v %q\n%v", n.val.Class, n.val.Repr, lineCol(n.Pos())))
+	d.printf("\tn%v -> n%v [label=%q];\n", parent, id, label)
+	if n.left != nil {
+		d.node(n.left, id, "left")
+	}
+	if n.right != nil {
+		d.node(n.right, id, "right")
+	}
+	for _, b := range n.blocks() {
+		for i, c := range b.nodes {
+			d.node(c, id, fmt.Sprintf("%v %v", b.name, i+1))
+		}
+	}
+}
+
+// DumpTokens writes `tokens` to `w` as a table, with the position, type and
+// quoted text of each token
+func DumpTokens(w io.Writer, tokens []Token) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "POS\tTYPE\tREPR\n")
+	for _, t := range tokens {
+		fmt.Fprintf(tw, "%v\t%v\t%q\n", lineCol(t.Pos), t.Class, t.Repr)
+	}
+	return tw.Flush()
+}
+
+// jsonToken is how a token is written as JSON
+type jsonToken struct {
+	Line  int    `json:"line"`
+	Col   int    `json:"col"`
+	Class string `json:"class"`
+	Repr  string `json:"repr"`
+}
+
+// DumpTokensJSON writes `tokens` to `w` as JSON lines, with an object for
+// each token
+func DumpTokensJSON(w io.Writer, tokens []Token) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, t := range tokens {
+		if err := enc.Encode(jsonToken{Line: t.Pos.Line, Col: t.Pos.Col, Class: t.Class.String(), Repr: t.Repr}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
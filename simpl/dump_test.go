@@ -0,0 +1,151 @@
+package simpl
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	input := "xs = [1]\nwhile len(xs) < 2\nappend(xs, \"a\")\nend"
+	cases := []struct {
+		format   string
+		dump     func(w io.Writer, lines []*Node) error
+		expected string
+	}{
+		{
+			format: "text",
+			dump:   DumpText,
+			expected: `assignment "=" 1:4
+  left: variable "xs" 1:1
+  right: list "[]" 1:6
+    args:
+      num "1" 1:7
+keyword "while" 2:1
+  right: boolop "<" 2:15
+    left: call "len" 2:7
+      args:
+        variable "xs" 2:11
+    right: num "2" 2:17
+  body:
+    call "append" 3:1
+      args:
+        variable "xs" 3:8
+        str "a" 3:12
+`,
+		},
+		{
+			format: "json",
+			dump: func(w io.Writer, lines []*Node) error {
+				return DumpJSON(w, lines[:1])
+			},
+			expected: `[
+  {
+    "class": "assignment",
+    "repr": "=",
+    "line": 1,
+    "col": 4,
+    "left": {
+      "class": "variable",
+      "repr": "xs",
+      "line": 1,
+      "col": 1
+    },
+    "right": {
+      "class": "list",
+      "repr": "[]",
+      "line": 1,
+      "col": 6,
+      "args": [
+        {
+          "class": "num",
+          "repr": "1",
+          "line": 1,
+          "col": 7
+        }
+      ]
+    }
+  }
+]
+`,
+		},
+		{
+			format: "dot",
+			dump: func(w io.Writer, lines []*Node) error {
+				return DumpDot(w, lines[1:])
+			},
+			expected: `digraph ast {
+	node [shape=box, fontname=monospace];
+	n0 [label="program"];
+	n1 [label="keyword \"while\"\n2:1"];
+	n0 -> n1 [label="1"];
+	n2 [label="boolop \"<\"\n2:15"];
+	n1 -> n2 [label="right"];
+	n3 [label="call \"len\"\n2:7"];
+	n2 -> n3 [label="left"];
+	n4 [label="variable \"xs\"\n2:11"];
+	n3 -> n4 [label="args 1"];
+	n5 [label="num \"2\"\n2:17"];
+	n2 -> n5 [label="right"];
+	n6 [label="call \"append\"\n3:1"];
+	n1 -> n6 [label="body 1"];
+	n7 [label="variable \"xs\"\n3:8"];
+	n6 -> n7 [label="args 1"];
+	n8 [label="str \"a\"\n3:12"];
+	n6 -> n8 [label="args 2"];
+}
+`,
+		},
+	}
+	l := Lexer{In: strings.NewReader(input), File: "test"}
+	tkns, _ := l.Lex()
+	p := Parser{Tokens: tkns}
+	if errs := p.Parse(); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	for _, test := range cases {
+		t.Run(test.format, func(t *testing.T) {
+			out := strings.Builder{}
+			if err := test.dump(&out, p.Lines); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != test.expected {
+				t.Errorf("expected:\n%v\ngot:\n%v", test.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestDumpTokens(t *testing.T) {
+	l := Lexer{In: strings.NewReader("if -i < 1 print \"a b\"\n")}
+	tkns, _ := l.Lex()
+	table := strings.Builder{}
+	if err := DumpTokens(&table, tkns); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `POS   TYPE      REPR
+1:1   keyword   "if"
+1:4   operator  "-"
+1:5   variable  "i"
+1:7   boolop    "<"
+1:9   num       "1"
+1:11  builtin   "print"
+1:17  str       "a b"
+1:22  newline   "\\n"
+`
+	if table.String() != expected {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, table.String())
+	}
+
+	lines := strings.Builder{}
+	if err := DumpTokensJSON(&lines, tkns[:3]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = `{"line":1,"col":1,"class":"keyword","repr":"if"}
+{"line":1,"col":4,"class":"operator","repr":"-"}
+{"line":1,"col":5,"class":"variable","repr":"i"}
+`
+	if lines.String() != expected {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, lines.String())
+	}
+}
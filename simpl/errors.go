@@ -0,0 +1,60 @@
+package simpl
+
+import "fmt"
+
+// LexError is an error found while lexing
+type LexError struct {
+	Pos Pos
+	Err error
+}
+
+func (e *LexError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Pos, e.Err)
+}
+
+func (e *LexError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError is an error found while parsing
+type ParseError struct {
+	Pos Pos
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Pos, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// RuntimeError is an error found while interpreting
+type RuntimeError struct {
+	Pos Pos
+	Err error
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Pos, e.Err)
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// runtimeErrorf makes a RuntimeError at the position of `n`
+func runtimeErrorf(n *Node, format string, a ...interface{}) error {
+	return &RuntimeError{Pos: n.Pos(), Err: fmt.Errorf(format, a...)}
+}
+
+// ExitError is returned when a program stops itself with exit(), with the
+// exit code it gave
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %v", e.Code)
+}
@@ -0,0 +1,112 @@
+package simpl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// builtinReadfile returns what's in a file
+func builtinReadfile(in *Interpreter, args []Value) (Value, error) {
+	path, err := in.allowed(args[0], in.read, "read")
+	if err != nil {
+		return Value{}, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Value{}, err
+	}
+	return StringValue(string(b)), nil
+}
+
+// builtinWritefile replaces what's in a file, making it if it isn't there
+func builtinWritefile(in *Interpreter, args []Value) (Value, error) {
+	return Value{}, in.writeFile(args, os.O_TRUNC)
+}
+
+// builtinAppendfile adds to the end of a file, making it if it isn't there
+func builtinAppendfile(in *Interpreter, args []Value) (Value, error) {
+	return Value{}, in.writeFile(args, os.O_APPEND)
+}
+
+// writeFile writes `args[1]` to the file `args[0]`, opened with `flag`
+func (in *Interpreter) writeFile(args []Value, flag int) error {
+	path, err := in.allowed(args[0], in.write, "write")
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|flag, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(args[1].String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// builtinExists returns whether a file is there
+func builtinExists(in *Interpreter, args []Value) (Value, error) {
+	path, err := in.allowed(args[0], in.read, "read")
+	if err != nil {
+		return Value{}, err
+	}
+	_, err = os.Stat(path)
+	return BoolValue(err == nil), nil
+}
+
+// allowed returns the path `v` with symbolic links resolved, if it's in one of
+// the directories `dirs`, which programs are allowed to `do` files in
+func (in *Interpreter) allowed(v Value, dirs []string, do string) (string, error) {
+	name, err := v.str()
+	if err != nil {
+		return "", err
+	}
+	path, err := resolve(name)
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range dirs {
+		dir, err := resolve(dir)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("not allowed to %v '%v'", do, name)
+}
+
+// resolve returns the absolute path of `path` with symbolic links resolved,
+// so they can't be used to get out of a directory. The file doesn't have to
+// be there, but if it isn't, the links in the path to it are still resolved.
+func resolve(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err == nil {
+		return real, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	if _, err := os.Lstat(abs); err == nil {
+		// a link to a file that isn't there, which could be anywhere
+		return "", fmt.Errorf("'%v' is a broken link", path)
+	}
+	parent := filepath.Dir(abs)
+	if parent == abs {
+		return abs, nil
+	}
+	real, err = resolve(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(real, filepath.Base(abs)), nil
+}
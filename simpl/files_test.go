@@ -0,0 +1,104 @@
+package simpl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "allowed")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{allowed, outside} {
+		if err := os.Mkdir(d, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(allowed, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "new"), filepath.Join(allowed, "broken")); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		input    string
+		opts     []Option
+		expected string
+		err      string
+	}{
+		{
+			name: "allowed",
+			input: `f = dir + "/allowed/sub/../f.txt"
+print exists(f) + " "
+writefile(f, "a\n")
+appendfile(f, [1])
+print exists(f) + " " + readfile(f)
+writefile(f, "b")
+print readfile(f)`,
+			opts:     []Option{AllowRead(allowed), AllowWrite(allowed)},
+			expected: "false true a\n[1]b",
+		},
+		{
+			name:  "no access",
+			input: `exists(dir + "/allowed/f.txt")`,
+			err:   "'exists' not allowed to read '" + allowed + "/f.txt'",
+		},
+		{
+			name:  "read only",
+			input: `writefile(dir + "/allowed/f.txt", "")`,
+			opts:  []Option{AllowRead(allowed)},
+			err:   "'writefile' not allowed to write '" + allowed + "/f.txt'",
+		},
+		{
+			name:  "getting out with ..",
+			input: `readfile(dir + "/allowed/../outside/secret")`,
+			opts:  []Option{AllowRead(allowed)},
+			err:   "'readfile' not allowed to read '" + allowed + "/../outside/secret'",
+		},
+		{
+			name:  "getting out with a link",
+			input: `readfile(dir + "/allowed/link/secret")`,
+			opts:  []Option{AllowRead(allowed)},
+			err:   "'readfile' not allowed to read '" + allowed + "/link/secret'",
+		},
+		{
+			name:  "getting out with a broken link",
+			input: `writefile(dir + "/allowed/broken", "")`,
+			opts:  []Option{AllowWrite(allowed)},
+			err:   "'writefile' '" + allowed + "/broken' is a broken link",
+		},
+		{
+			name:  "missing file",
+			input: `readfile(dir + "/allowed/missing")`,
+			opts:  []Option{AllowRead(allowed)},
+			err:   "'readfile' open " + allowed + "/missing: no such file or directory",
+		},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			setDir := func(i *Interpreter) {
+				i.Vars["dir"] = StringValue(dir)
+			}
+			out, err := run(t, test.input, append(test.opts, setDir)...)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.err != "" && (err == nil || !strings.HasSuffix(err.Error(), test.err)):
+				t.Fatalf("expected error %v got %v", test.err, err)
+			}
+			if out != test.expected {
+				t.Errorf("expected %q got %q", test.expected, out)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new")); !os.IsNotExist(err) {
+		t.Errorf("expected the broken link not to be written through, got %v", err)
+	}
+}
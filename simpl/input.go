@@ -0,0 +1,71 @@
+package simpl
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// builtinInput prints a prompt if it's given one, and returns the next line
+// of input without its newline, or nil at the end of the input
+func builtinInput(in *Interpreter, args []Value) (Value, error) {
+	if len(args) == 1 {
+		fmt.Fprint(in.w, args[0])
+	}
+	line, err := in.readLine()
+	if err != nil || line.Kind == Nil {
+		return line, err
+	}
+	return StringValue(strings.TrimRight(line.s, "\r\n")), nil
+}
+
+// builtinReadline returns the next line of input including its newline, so
+// that an empty line is "\n" and only the end of the input is nil
+func builtinReadline(in *Interpreter, args []Value) (Value, error) {
+	return in.readLine()
+}
+
+// readLine reads the next line of input, which is nil at the end of it
+func (in *Interpreter) readLine() (Value, error) {
+	if in.r == nil {
+		return Value{}, nil
+	}
+	line, err := in.r.ReadString('\n')
+	if err == io.EOF {
+		if line == "" {
+			return Value{}, nil
+		}
+	} else if err != nil {
+		return Value{}, err
+	}
+	return StringValue(line), nil
+}
+
+// builtinInt makes an Int out of a number, truncating Floats, or out of a
+// string holding a number, ignoring whitespace around it
+func builtinInt(in *Interpreter, args []Value) (Value, error) {
+	n, err := parseArg(args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	return toInt(n, math.Trunc)
+}
+
+// builtinFloat makes a Float out of a number, or out of a string holding a
+// number, ignoring whitespace around it
+func builtinFloat(in *Interpreter, args []Value) (Value, error) {
+	n, err := parseArg(args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	return FloatValue(n.Float()), nil
+}
+
+// parseArg returns `v` as a number, parsing it if it's a string
+func parseArg(v Value) (Value, error) {
+	if v.Kind == String {
+		return parseNumber(strings.TrimSpace(v.s))
+	}
+	return v.number()
+}
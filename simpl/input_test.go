@@ -0,0 +1,75 @@
+package simpl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInput(t *testing.T) {
+	cases := []struct {
+		name     string
+		program  string
+		input    string
+		expected string
+	}{
+		{
+			name: "input",
+			program: `name = input("name? ")
+print "hi " + name + "!"
+print input() + input()`,
+			input:    "bob\r\n\nlast",
+			expected: "name? hi bob!last",
+		},
+		{
+			name: "readline",
+			program: `total = 0
+line = readline()
+while line
+total = total + int(line)
+line = readline()
+end
+print total + " " + readline()`,
+			input:    "1\n 2 \n-3\n40",
+			expected: "40 nil",
+		},
+		{
+			name:     "no input",
+			program:  `print input() + " " + readline()`,
+			expected: "nil nil",
+		},
+		{
+			name:     "parsing numbers",
+			program:  `print ( int("7") + int(" -2.9 ") + int(true) ) + " " + float("1e3") + " " + float(2) / 4`,
+			expected: "6 1000 0.5",
+		},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			var opts []Option
+			if test.input != "" {
+				opts = append(opts, Input(strings.NewReader(test.input)))
+			}
+			if out := runProgram(t, test.program, opts...); out != test.expected {
+				t.Errorf("expected %q got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestParsingErrors(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{input: `int("abc")`, expected: "1:1: 'int' bad number 'abc'"},
+		{input: `float([])`, expected: "1:1: 'float' expected a number, got list '[]'"},
+		{input: `int(1e300)`, expected: "1:1: 'int' 1e+300 can't be made into an int"},
+	}
+	for _, test := range cases {
+		t.Run(test.input, func(t *testing.T) {
+			if err := runError(t, test.input); err.Error() != test.expected {
+				t.Errorf("expected %v got %v", test.expected, err)
+			}
+		})
+	}
+}
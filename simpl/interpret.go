@@ -1,146 +1,603 @@
 package simpl
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"io"
-	"log"
-	"strconv"
+	"math"
+	"math/rand"
 )
 
 // Interpreter interprets simple ASTs
 type Interpreter struct {
 	Lines  *[]*Node
-	Vars   map[string]interface{}
+	Vars   map[string]Value
 	w      io.Writer
-	calls  Stack
-	retval interface{}
+	retval Value
+	pc     int                         // index in `Lines` of the next line to run
+	next   int                         // index of the first line in `Lines` that hasn't been run yet
+	labels map[string]int              // index in `Lines` of each label
+	jumped bool                        // whether a goto is unwinding out of the blocks it was in
+	ret    bool                        // whether a return is unwinding out of the blocks it was in
+	funcs  map[string]*Node            // function definitions, by name
+	frames []*frame                    // the function calls being run
+	rand   *rand.Rand                  // the source of random()
+	host   map[string]builtin          // functions added with Register, by name
+	r      *bufio.Reader               // where input() reads from
+	read   []string                    // directories that programs can read files in
+	write  []string                    // directories that programs can write files in
+	env    func(string) (string, bool) // looks up environment variables
+	walk   bool                        // whether to walk the trees in `Lines` instead of compiling them
+	prog   program                     // the lines compiled so far
 }
 
-// NewInterpreter creates a new Interpreter
-func NewInterpreter(lines *[]*Node, writer io.Writer) Interpreter {
+// frame holds the state of a function call
+type frame struct {
+	vars   map[string]Value
+	retval Value
+}
+
+// maxDepth is how deep calls can be nested before it's an error
+const maxDepth = 10000
+
+// Option configures an Interpreter made by NewInterpreter
+type Option func(*Interpreter)
+
+// Input makes programs read their input from `r`. Without it, they don't
+// have any.
+func Input(r io.Reader) Option {
+	return func(in *Interpreter) {
+		in.r = bufio.NewReader(r)
+	}
+}
+
+// AllowRead lets programs read the files in `dirs`, and in the directories
+// inside them. Without it, they can't read any files.
+func AllowRead(dirs ...string) Option {
+	return func(in *Interpreter) {
+		in.read = append(in.read, dirs...)
+	}
+}
+
+// AllowWrite lets programs write to the files in `dirs`, and in the
+// directories inside them. Without it, they can't write to any files.
+func AllowWrite(dirs ...string) Option {
+	return func(in *Interpreter) {
+		in.write = append(in.write, dirs...)
+	}
+}
+
+// Args sets the variable `args` to a list of `args`, for programs to get the
+// arguments they were run with
+func Args(args ...string) Option {
+	return func(in *Interpreter) {
+		elems := make([]Value, len(args))
+		for i, arg := range args {
+			elems[i] = StringValue(arg)
+		}
+		in.set("args", ListValue(elems))
+	}
+}
+
+// Env lets programs look up environment variables with `lookup`, which is
+// usually os.LookupEnv. Without it, there aren't any environment variables.
+func Env(lookup func(name string) (string, bool)) Option {
+	return func(in *Interpreter) {
+		in.env = lookup
+	}
+}
+
+// Walk makes the Interpreter run programs by walking the trees in `Lines`,
+// rather than by compiling them to bytecode first. It's slower, and is there
+// to check the bytecode against.
+func Walk() Option {
+	return func(in *Interpreter) {
+		in.walk = true
+	}
+}
+
+// NewInterpreter creates a new Interpreter, which prints to `writer`
+func NewInterpreter(lines *[]*Node, writer io.Writer, opts ...Option) Interpreter {
 	i := Interpreter{Lines: lines, w: writer}
-	i.Vars = make(map[string]interface{})
+	i.Vars = make(map[string]Value)
+	for _, opt := range opts {
+		opt(&i)
+	}
 	return i
 }
 
-// Interpret interprets the ASTs `Lines` in the Interpreter
-func (in *Interpreter) Interpret() interface{} {
-	for i := len(*in.Lines) - 1; i >= 0; i-- {
-		in.calls.Push((*in.Lines)[i])
+// Seed seeds the source of random numbers, so the numbers are the same every
+// time for the same seed. Otherwise they're different each time.
+func (in *Interpreter) Seed(seed int64) {
+	in.rand = rand.New(rand.NewSource(seed))
+}
+
+// Register makes the Go function `fn` callable from programs as `name`,
+// taking `args` arguments, or any number of them if `args` is -1. Functions
+// defined in a program hide registered ones with the same name, which in turn
+// hide the builtin ones. If `fn` returns an error, the program stops with a
+// *RuntimeError that wraps it.
+//
+// Register panics if `name` isn't a name programs can call, such as a
+// keyword, or if `args` is less than -1.
+func (in *Interpreter) Register(name string, args int, fn func(args []Value) (Value, error)) {
+	if !isName(name) {
+		panic(fmt.Sprintf("simpl: can't register %q, which isn't a name programs can call", name))
+	}
+	if args < -1 {
+		panic(fmt.Sprintf("simpl: can't register %q taking %v arguments", name, args))
+	}
+	if in.host == nil {
+		in.host = make(map[string]builtin)
+	}
+	min := args
+	if args == -1 {
+		min = 0
+	}
+	in.host[name] = builtin{min: min, max: args, fn: func(_ *Interpreter, args []Value) (Value, error) {
+		return fn(args)
+	}}
+}
+
+// isName returns whether `name` is lexed as a variable, so that programs can
+// call it
+func isName(name string) bool {
+	if name == "" {
+		return false
+	}
+	class, err := classifyToken(name)
+	return err == nil && class == Var
+}
+
+// Interpret interprets the ASTs `Lines` in the Interpreter. Lines that
+// were run by a previous call are skipped, so more lines can be appended to
+// `Lines` and interpreted without losing the state of the earlier ones.
+//
+// Interpreting stops at the first error, which is returned as a
+// *RuntimeError.
+func (in *Interpreter) Interpret() (Value, error) {
+	in.retval = Value{}
+	// labels and functions can be used before the lines that define them
+	for i := in.next; i < len(*in.Lines); i++ {
+		line := (*in.Lines)[i]
+		switch {
+		case line.val.Class == Label:
+			if in.labels == nil {
+				in.labels = make(map[string]int)
+			}
+			in.labels[line.val.Repr] = i
+		case isKeyword(line, "func"):
+			if in.funcs == nil {
+				in.funcs = make(map[string]*Node)
+			}
+			in.funcs[line.right.val.Repr] = line
+		}
 	}
-	for in.calls.Peek() != nil {
-		cur := in.calls.Pop()
-		//		cur.PrintTree()
-		in.retval = in.eval(cur)
+	start := in.next
+	in.next = len(*in.Lines)
+	if !in.walk {
+		in.prog.compileLines(*in.Lines, start)
+		return in.execute(start)
 	}
-	return in.retval
+	for in.pc = start; in.pc < len(*in.Lines); {
+		cur := (*in.Lines)[in.pc]
+		in.pc++
+		retval, err := in.eval(cur)
+		if err != nil {
+			in.frames = nil
+			in.ret = false
+			return Value{}, err
+		}
+		in.jumped = false
+		in.retval = retval
+	}
+	return in.retval, nil
 }
 
 // eval evaluates a node
-func (in *Interpreter) eval(n *Node) interface{} {
+func (in *Interpreter) eval(n *Node) (Value, error) {
 	if n == nil {
-		return 0.0
+		return Value{}, nil
 	}
 	switch n.val.Class {
 	case Var:
-		return in.Vars[n.val.Repr]
+		return in.get(n.val.Repr), nil
+	case Call:
+		return in.call(n)
 	case Str:
-		return n.val.Repr
+		return StringValue(n.val.Repr), nil
 	case Num:
-		v, _ := strconv.ParseFloat(n.val.Repr, 64)
-		return v
-	case Operator:
-		left := in.eval(n.left)
-		if left == nil {
-			left = 0.0
+		v, err := n.number()
+		if err != nil {
+			return Value{}, &RuntimeError{Pos: n.Pos(), Err: err}
 		}
-		right := in.eval(n.right)
-		if right == nil {
-			right = 0.0
+		return v, nil
+	case Boolean:
+		return BoolValue(n.val.Repr == "true"), nil
+	case ListLit:
+		elems := make([]Value, len(n.args))
+		for i, arg := range n.args {
+			val, err := in.eval(arg)
+			if err != nil {
+				return Value{}, err
+			}
+			elems[i] = val
 		}
-		switch n.val.Repr {
-		case "+":
-			switch left.(type) {
-			case string:
-				return stringAdd(left, right)
-			case float64:
-				switch right.(type) {
-				case float64:
-					return left.(float64) + right.(float64)
-				case string:
-					return stringAdd(left, right)
-				}
+		return ListValue(elems), nil
+	case MapLit:
+		entries := make(map[string]Value, len(n.args))
+		for _, entry := range n.args {
+			k, err := in.eval(entry.left)
+			if err != nil {
+				return Value{}, err
 			}
-		case "-":
-			return left.(float64) - right.(float64)
-		case "*":
-			return left.(float64) * right.(float64)
-		case "/":
-			return left.(float64) / right.(float64)
-		case "%":
-			return int(left.(float64)) % int(right.(float64))
-		}
-	case Boolop:
-		left := toFloat64(in.eval(n.left))
-		right := toFloat64(in.eval(n.right))
-		switch n.val.Repr {
-		// 0 is false
-		case "&":
-			return (left != 0) && (right != 0)
-		case "|":
-			return (left != 0) || (right != 0)
-		case "==":
-			return left == right
-		case "!=":
-			return left != right
-		case ">":
-			return left > right
-		case "<":
-			return left < right
+			val, err := in.eval(entry.right)
+			if err != nil {
+				return Value{}, err
+			}
+			name, err := key(k)
+			if err != nil {
+				return Value{}, &RuntimeError{Pos: entry.left.Pos(), Err: err}
+			}
+			entries[name] = val
+		}
+		return MapValue(entries), nil
+	case Index:
+		list, err := in.eval(n.left)
+		if err != nil {
+			return Value{}, err
+		}
+		i, err := in.eval(n.right)
+		if err != nil {
+			return Value{}, err
+		}
+		val, err := list.index(i)
+		if err != nil {
+			return Value{}, &RuntimeError{Pos: n.Pos(), Err: err}
+		}
+		return val, nil
+	case Unary:
+		right, err := in.eval(n.right)
+		if err != nil {
+			return Value{}, err
+		}
+		if n.val.Repr != "-" {
+			return BoolValue(!right.Bool()), nil
 		}
+		val, err := negate(right)
+		if err != nil {
+			return Value{}, &RuntimeError{Pos: n.Pos(), Err: err}
+		}
+		return val, nil
+	case Operator, Boolop:
+		left, err := in.eval(n.left)
+		if err != nil {
+			return Value{}, err
+		}
+		// the right side of `&` and `|` is only run if it makes a difference
+		switch {
+		case n.val.Repr == "&" && !left.Bool():
+			return BoolValue(false), nil
+		case n.val.Repr == "|" && left.Bool():
+			return BoolValue(true), nil
+		}
+		right, err := in.eval(n.right)
+		if err != nil {
+			return Value{}, err
+		}
+		val, err := Operate(n.val.Repr, left, right)
+		if err != nil {
+			return Value{}, &RuntimeError{Pos: n.Pos(), Err: err}
+		}
+		return val, nil
 	case Builtin:
-		right := in.eval(n.right)
+		right, err := in.eval(n.right)
+		if err != nil {
+			return Value{}, err
+		}
 		switch n.val.Repr {
 		case "print":
-			switch right := right.(type) {
-			case string:
-				fmt.Fprintf(in.w, ""+right+"")
-			default:
-				fmt.Fprintf(in.w, "%v", right)
-			}
+			fmt.Fprint(in.w, right)
 		case "goto":
-			for i := len(*in.Lines) - 1; i > int(right.(float64))-2; i-- {
-				in.calls.Push((*in.Lines)[i])
+			// a name is always a label, anything else is a line number
+			var target int
+			if n.right.val.Class == Var {
+				i, ok := in.labels[n.right.val.Repr]
+				if !ok {
+					return Value{}, runtimeErrorf(n.right, "unknown label '%v'", n.right.val.Repr)
+				}
+				target = i
+			} else {
+				line, err := right.number()
+				if err != nil {
+					return Value{}, runtimeErrorf(n, "'goto' %v", err)
+				}
+				if line.Int() < 1 || line.Int() > int64(len(*in.Lines)) {
+					return Value{}, runtimeErrorf(n, "cannot goto line %v, there are %v lines", line, len(*in.Lines))
+				}
+				target = int(line.Int()) - 1
 			}
+			// skip the rest of the blocks this is in, and run from `target`
+			in.jumped = true
+			in.pc = target
 		}
 	case Assignment:
-		variable := n.left.val.Repr
-		right := in.eval(n.right)
-		in.Vars[variable] = right
+		if n.left == nil || n.left.val.Class != Var && n.left.val.Class != Index {
+			return Value{}, runtimeErrorf(n, "can only assign to a variable")
+		}
+		right, err := in.eval(n.right)
+		if err != nil {
+			return Value{}, err
+		}
+		if n.left.val.Class == Index {
+			return Value{}, in.setIndex(n.left, right)
+		}
+		in.set(n.left.val.Repr, right)
 	case Keyword:
-		if toFloat64(in.eval(n.left)) != 0 {
-			in.eval(n.right)
+		switch n.val.Repr {
+		case "if":
+			cond, err := in.eval(n.left)
+			if err != nil {
+				return Value{}, err
+			}
+			switch {
+			case n.right == nil: // a block
+				lines := n.body
+				if !cond.Bool() {
+					lines = n.alt
+				}
+				if err := in.run(lines); err != nil {
+					return Value{}, err
+				}
+			case cond.Bool():
+				if _, err := in.eval(n.right); err != nil {
+					return Value{}, err
+				}
+			}
+		case "while":
+			for !in.unwinding() {
+				cond, err := in.eval(n.right)
+				if err != nil {
+					return Value{}, err
+				}
+				if !cond.Bool() {
+					break
+				}
+				if err := in.run(n.body); err != nil {
+					return Value{}, err
+				}
+			}
+		case "for":
+			return Value{}, in.evalFor(n)
+		case "func":
+			// functions are defined before anything is run
+		case "return":
+			f := in.frames[len(in.frames)-1]
+			f.retval = Value{}
+			if n.right != nil {
+				val, err := in.eval(n.right)
+				if err != nil {
+					return Value{}, err
+				}
+				f.retval = val
+			}
+			in.ret = true
+		default:
+			return Value{}, runtimeErrorf(n, "unexpected '%v'", n.val.Repr)
+		}
+	case Label:
+		if n.right != nil {
+			return in.eval(n.right)
 		}
 	default:
-		log.Fatalf("cannot evaluate node of type %v", n.val.Class)
+		return Value{}, runtimeErrorf(n, "cannot evaluate node of type %v", n.val.Class)
+	}
+	return Value{}, nil
+}
+
+// evalFor runs the `for` loop `n`, which looks like `for i = start to end`.
+// The loop variable counts up by one from start to end, including end, no
+// matter what the body does to it. It's an Int unless start or end is a
+// Float.
+func (in *Interpreter) evalFor(n *Node) error {
+	variable := n.right.left.val.Repr
+	start, err := in.evalNumber(n, n.right.right.left)
+	if err != nil {
+		return err
+	}
+	end, err := in.evalNumber(n, n.right.right.right)
+	if err != nil {
+		return err
+	}
+	start, end, err = forBounds(start, end)
+	if err != nil {
+		return runtimeErrorf(n, "'for' %v", err)
+	}
+	if start.Kind == Int {
+		for i := start.i; i <= end.i && !in.unwinding(); i++ {
+			in.set(variable, IntValue(i))
+			if err := in.run(n.body); err != nil {
+				return err
+			}
+			if i == end.i {
+				break // so that counting past the biggest int doesn't wrap around
+			}
+		}
+		return nil
+	}
+	for i := start.f; i <= end.f && !in.unwinding(); i++ {
+		in.set(variable, FloatValue(i))
+		if err := in.run(n.body); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func toFloat64(val interface{}) float64 {
-	switch val := val.(type) {
-	case bool:
-		if val {
-			return 1
+// maxCount is the biggest Float a for loop counts up to, since past it,
+// adding one doesn't change a Float
+const maxCount = 1 << 53
+
+// forBounds returns the first and last values of the counter of a for loop
+// from `start` to `end`, which are Ints unless start or end is a Float. Loops
+// with Floats that can't be counted through one at a time are an error.
+func forBounds(start, end Value) (Value, Value, error) {
+	if start.Kind == Int && end.Kind == Int {
+		return start, end, nil
+	}
+	first, last := start.Float(), end.Float()
+	switch {
+	case math.IsInf(first, 0) || math.IsNaN(first) || math.IsInf(last, 0) || math.IsNaN(last),
+		first <= last && (first < -maxCount || last >= maxCount):
+		return Value{}, Value{}, fmt.Errorf("can't count from %v to %v one at a time", start, end)
+	}
+	return FloatValue(first), FloatValue(last), nil
+}
+
+// setIndex sets the element of a list picked out by the index `n` to `val`
+func (in *Interpreter) setIndex(n *Node, val Value) error {
+	list, err := in.eval(n.left)
+	if err != nil {
+		return err
+	}
+	i, err := in.eval(n.right)
+	if err != nil {
+		return err
+	}
+	if err := list.setIndex(i, val); err != nil {
+		return &RuntimeError{Pos: n.Pos(), Err: err}
+	}
+	return nil
+}
+
+// call runs the function named by the call `n`, returning what it returns
+func (in *Interpreter) call(n *Node) (Value, error) {
+	def, ok := in.funcs[n.val.Repr]
+	if !ok {
+		b, ok := in.builtin(n.val.Repr)
+		if !ok {
+			return Value{}, runtimeErrorf(n, "unknown function '%v'", n.val.Repr)
 		}
-		return 0
-	case int:
-		return float64(val)
-	default:
-		return val.(float64)
+		return in.callBuiltin(n, b)
+	}
+	params := def.right.args
+	if len(n.args) != len(params) {
+		return Value{}, runtimeErrorf(n, "'%v' takes %v arguments, got %v", n.val.Repr, len(params), len(n.args))
+	}
+	if len(in.frames) >= maxDepth {
+		return Value{}, runtimeErrorf(n, "calls are nested more than %v deep", maxDepth)
 	}
+	f := &frame{vars: make(map[string]Value)}
+	for i, arg := range n.args {
+		val, err := in.eval(arg)
+		if err != nil {
+			return Value{}, err
+		}
+		f.vars[params[i].val.Repr] = val
+	}
+	in.frames = append(in.frames, f)
+	err := in.run(def.body)
+	in.frames = in.frames[:len(in.frames)-1]
+	in.ret = false
+	if err != nil {
+		return Value{}, err
+	}
+	return f.retval, nil
+}
+
+// callBuiltin runs the builtin or registered function `b` for the call `n`
+func (in *Interpreter) callBuiltin(n *Node, b builtin) (Value, error) {
+	if err := b.arity(len(n.args)); err != nil {
+		return Value{}, runtimeErrorf(n, "'%v' %v", n.val.Repr, err)
+	}
+	args := make([]Value, len(n.args))
+	for i, arg := range n.args {
+		val, err := in.eval(arg)
+		if err != nil {
+			return Value{}, err
+		}
+		args[i] = val
+	}
+	val, err := b.fn(in, args)
+	if err != nil {
+		return Value{}, builtinError(n.Pos(), n.val.Repr, err)
+	}
+	return val, nil
 }
 
-func stringAdd(left, right interface{}) string {
-	return fmt.Sprintf("%v", left) + fmt.Sprintf("%v", right)
+// builtin looks up the registered or builtin function `name`
+func (in *Interpreter) builtin(name string) (builtin, bool) {
+	if b, ok := in.host[name]; ok {
+		return b, true
+	}
+	b, ok := builtins[name]
+	return b, ok
+}
+
+// builtinError makes the error `err` from the builtin `name` into a
+// RuntimeError at `pos`, unless the program is exiting
+func builtinError(pos Pos, name string, err error) error {
+	var exit *ExitError
+	if errors.As(err, &exit) {
+		return exit
+	}
+	return &RuntimeError{Pos: pos, Err: fmt.Errorf("'%v' %w", name, err)}
+}
+
+// get returns the value of the variable `name`. Inside a function, its local
+// variables hide the global ones in `Vars`.
+func (in *Interpreter) get(name string) Value {
+	if len(in.frames) > 0 {
+		if val, ok := in.frames[len(in.frames)-1].vars[name]; ok {
+			return val
+		}
+	}
+	return in.Vars[name]
+}
+
+// set sets the variable `name`, which is local to the current function if
+// there is one
+func (in *Interpreter) set(name string, val Value) {
+	if len(in.frames) > 0 {
+		in.frames[len(in.frames)-1].vars[name] = val
+		return
+	}
+	if in.Vars == nil {
+		in.Vars = make(map[string]Value)
+	}
+	in.Vars[name] = val
+}
+
+// unwinding returns whether a goto or return is skipping the rest of the
+// blocks it was in
+func (in *Interpreter) unwinding() bool {
+	return in.jumped || in.ret
+}
+
+// run runs the lines of a block, stopping early if one of them jumps
+// somewhere else with goto, or returns from a function
+func (in *Interpreter) run(lines []*Node) error {
+	for _, line := range lines {
+		if _, err := in.eval(line); err != nil {
+			return err
+		}
+		if in.unwinding() {
+			return nil
+		}
+	}
+	return nil
+}
+
+// evalNumber evaluates `operand`, an operand of `n`, which has to be a number
+func (in *Interpreter) evalNumber(n, operand *Node) (Value, error) {
+	val, err := in.eval(operand)
+	if err != nil {
+		return Value{}, err
+	}
+	num, err := val.number()
+	if err != nil {
+		return Value{}, runtimeErrorf(n, "'%v' %v", n.val.Repr, err)
+	}
+	return num, nil
 }
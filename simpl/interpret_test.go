@@ -1,6 +1,8 @@
 package simpl
 
 import (
+	"errors"
+	"io"
 	"os"
 	"reflect"
 	"strings"
@@ -30,139 +32,219 @@ func TestOperators(t *testing.T) {
 	cases := []struct {
 		class    string
 		input    string
-		expected interface{}
+		expected Value
 	}{
 		{
 			class:    "arithmetic",
 			input:    "1 + 1",
-			expected: 2.0,
+			expected: IntValue(2),
 		},
 		{
 			class:    "arithmetic",
 			input:    "1 + 1 + 1",
-			expected: 3.0,
+			expected: IntValue(3),
 		},
 		{
 			class:    "arithmetic",
 			input:    "69 * 4 + 5",
-			expected: 281.0,
+			expected: IntValue(281),
 		},
 		{
 			class:    "arithmetic",
 			input:    "69 / 4 - 5",
-			expected: 12.25,
+			expected: FloatValue(12.25),
 		},
 		{
 			class:    "arithmetic",
 			input:    "420 + 69 * 6969 / 3000.4321",
-			expected: 580.2639166538713,
+			expected: FloatValue(580.2639166538713),
 		},
 		{
 			class:    "arithmetic",
 			input:    "0.420 + 0.69",
-			expected: 1.1099999999999999,
+			expected: FloatValue(1.1099999999999999),
 		},
 		{
 			class:    "arithmetic",
 			input:    ".42 + .6",
-			expected: 1.02,
+			expected: FloatValue(1.02),
 		},
 		{
 			class:    "arithmetic",
 			input:    "10 % 3",
-			expected: 1,
+			expected: IntValue(1),
 		},
 		{
 			class:    "arithmetic",
 			input:    "3 % 3",
-			expected: 0,
+			expected: IntValue(0),
 		},
 		{
 			class:    "arithmetic",
 			input:    "420 % 69",
-			expected: 6,
+			expected: IntValue(6),
 		},
 		{
 			class:    "arithmetic",
 			input:    "-420 % 69",
-			expected: -6,
+			expected: IntValue(-6),
 		},
 		{
 			class:    "arithmetic",
 			input:    "-420 % -69",
-			expected: -6,
+			expected: IntValue(-6),
 		},
 		{
 			class:    "boolean",
 			input:    "42 & 0",
-			expected: false,
+			expected: BoolValue(false),
 		},
 		{
 			class:    "boolean",
 			input:    "69 | 0",
-			expected: true,
+			expected: BoolValue(true),
 		},
 		{
 			class:    "boolean",
 			input:    "68 & 1 | 5 == 0",
-			expected: false,
+			expected: BoolValue(false),
 		},
 		{
 			class:    "boolean",
 			input:    "69 > 2",
-			expected: true,
+			expected: BoolValue(true),
 		},
 		// bc of the way i parse things you can do weird
 		// comparisons of numbers to booleans
 		{
 			class:    "boolean",
 			input:    "-2 > 3 > 5",
-			expected: false,
+			expected: BoolValue(false),
 		},
 		{
 			class:    "boolean",
 			input:    "-2 < 3 > 5",
-			expected: true,
+			expected: BoolValue(true),
 		},
 		{
 			class:    "boolean",
 			input:    "5 < 3",
-			expected: false,
+			expected: BoolValue(false),
 		},
 		{
 			class:    "boolean",
 			input:    "6.0 == 6",
-			expected: true,
+			expected: BoolValue(true),
 		},
 		{
 			class:    "boolean",
 			input:    "3 * 2 == 6",
-			expected: true,
+			expected: BoolValue(true),
 		},
 		{
 			class:    "boolean",
 			input:    "6 == 3 * 2",
-			expected: true,
+			expected: BoolValue(true),
 		},
 		{
 			class:    "boolean",
 			input:    "6 == 4 * 2",
-			expected: false,
+			expected: BoolValue(false),
 		},
 		{
 			class:    "boolean",
 			input:    "3 % 3 & 3 % 5",
-			expected: false,
+			expected: BoolValue(false),
 		},
 		{
 			class:    "boolean",
 			input:    "1 != 2",
-			expected: true,
+			expected: BoolValue(true),
 		},
 		{
 			class:    "boolean",
 			input:    "0 != 2 != 0",
-			expected: true,
+			expected: BoolValue(true),
+		},
+		{
+			class:    "boolean",
+			input:    "( 2 <= 2 ) & ( 2.5 >= 3 )",
+			expected: BoolValue(false),
+		},
+		{
+			class:    "boolean",
+			input:    "( 1 >= 1.0 ) & ( 0 <= false )",
+			expected: BoolValue(true),
+		},
+		{
+			class:    "comparison",
+			input:    "\"bob\" == \"bob\"",
+			expected: BoolValue(true),
+		},
+		{
+			class:    "comparison",
+			input:    "( \"apple\" < \"banana\" ) & ( \"b\" > \"abc\" ) & ( \"ab\" <= \"ab\" )",
+			expected: BoolValue(true),
+		},
+		{
+			class:    "comparison",
+			input:    "\"1\" == 1",
+			expected: BoolValue(false),
+		},
+		{
+			class:    "comparison",
+			input:    "1 == true",
+			expected: BoolValue(true),
+		},
+		{
+			class:    "comparison",
+			input:    "[1, \"a\", {\"b\": [2]}] == [1.0, \"a\", {\"b\": [2]}]",
+			expected: BoolValue(true),
+		},
+		{
+			class:    "comparison",
+			input:    "[1, 2] != [1]",
+			expected: BoolValue(true),
+		},
+		{
+			class:    "comparison",
+			input:    "x == y",
+			expected: BoolValue(true),
+		},
+		{
+			class:    "comparison",
+			input:    "x == 0",
+			expected: BoolValue(false),
+		},
+		{
+			class:    "unary",
+			input:    "-(2 + 3) * 2",
+			expected: IntValue(-10),
+		},
+		{
+			class:    "unary",
+			input:    "2 * -2.5",
+			expected: FloatValue(-5),
+		},
+		{
+			class:    "unary",
+			input:    "- -1 - -1",
+			expected: IntValue(2),
+		},
+		{
+			class:    "unary",
+			input:    "!0",
+			expected: BoolValue(true),
+		},
+		{
+			class:    "unary",
+			input:    "not 1 == 0",
+			expected: BoolValue(true),
+		},
+		{
+			class:    "unary",
+			input:    "!(1 == 1) | !\"\"",
+			expected: BoolValue(true),
 		},
 	}
 	l := Lexer{}
@@ -176,10 +258,732 @@ func TestOperators(t *testing.T) {
 			p := Parser{Tokens: tkns}
 			p.Parse()
 			i := Interpreter{Lines: &p.Lines}
-			res := i.Interpret()
+			res, rerr := i.Interpret()
+			if rerr != nil {
+				t.Errorf("unexpected error: %v", rerr)
+			}
 			if res != test.expected {
 				t.Errorf("expected %v got %v", test.expected, res)
 			}
 		})
 	}
 }
+
+func TestInterpretAppendedLines(t *testing.T) {
+	p := Parser{}
+	i := NewInterpreter(&p.Lines, os.Stdout)
+	lines := []struct {
+		input    string
+		expected Value
+	}{
+		{input: "i = 2", expected: Value{}},
+		{input: "i * 3", expected: IntValue(6)},
+		{input: "i = i + 1", expected: Value{}},
+		{input: "i", expected: IntValue(3)},
+		{input: "x = 7 % 3", expected: Value{}},
+		{input: "x * 2", expected: IntValue(2)},
+		{input: "x / 2", expected: FloatValue(0.5)},
+		{input: "x + 0.5", expected: FloatValue(1.5)},
+		{input: "\"x is \" + x", expected: StringValue("x is 1")},
+		{input: "true & x", expected: BoolValue(true)},
+		{input: "false | 0", expected: BoolValue(false)},
+	}
+	for _, line := range lines {
+		l := Lexer{In: strings.NewReader(line.input)}
+		tkns, _ := l.Lex()
+		p.Tokens = tkns
+		p.Parse()
+		if res, err := i.Interpret(); err != nil || res != line.expected {
+			t.Errorf("%v: expected %v got %v, %v", line.input, line.expected, res, err)
+		}
+	}
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "x = \"a\"\ny = x - 1",
+			expected: "test:2:7: '-' expected a number, got string 'a'",
+		},
+		{
+			input:    "5 % 0",
+			expected: "test:1:3: modulo by zero",
+		},
+		{
+			input:    "print 1\ngoto 3",
+			expected: "test:2:1: cannot goto line 3, there are 2 lines",
+		},
+		{
+			input:    "goto nowhere",
+			expected: "test:1:6: unknown label 'nowhere'",
+		},
+		{
+			input:    "f(1)",
+			expected: "test:1:1: unknown function 'f'",
+		},
+		{
+			input:    "func f(a)\nend\nf(1, 2)",
+			expected: "test:3:1: 'f' takes 1 arguments, got 2",
+		},
+		{
+			input:    "func f()\nreturn f()\nend\nf()",
+			expected: "test:2:8: calls are nested more than 10000 deep",
+		},
+		{
+			input:    "xs = [1, 2]\nprint xs[2]",
+			expected: "test:2:9: index 2 out of range for a list of length 2",
+		},
+		{
+			input:    "xs = [1, 2]\nxs[\"a\"] = 1",
+			expected: "test:2:3: index has to be an int, not string 'a'",
+		},
+		{
+			input:    "pop([])",
+			expected: "test:1:1: 'pop' can't pop from an empty list",
+		},
+		{
+			input:    "len(1)",
+			expected: "test:1:1: 'len' expected a list, map, or string, got int '1'",
+		},
+		{
+			input:    "append([])",
+			expected: "test:1:1: 'append' takes 2 arguments, got 1",
+		},
+		{
+			input:    "m = {\"a\": 1}\nprint m[\"b\"]",
+			expected: "test:2:8: no key \"b\" in the map",
+		},
+		{
+			input:    "m = {1: 1}",
+			expected: "test:1:6: map keys have to be strings, not int '1'",
+		},
+		{
+			input:    "has({}, [])",
+			expected: "test:1:1: 'has' map keys have to be strings, not list '[]'",
+		},
+		{
+			input:    "x = 1\nx[0] = 1",
+			expected: "test:2:2: can only index a list or a map, not int '1'",
+		},
+		{
+			input:    "x = \"a\"\ny = 1 + -x",
+			expected: "test:2:9: '-' expected a number, got string 'a'",
+		},
+		{
+			input:    "for i = 0 to 1e300\nend",
+			expected: "test:1:1: 'for' can't count from 0 to 1e+300 one at a time",
+		},
+		{
+			input:    "for i = 0 - 1e308 * 10 to 0\nend",
+			expected: "test:1:1: 'for' can't count from -Inf to 0 one at a time",
+		},
+		{
+			input:    "\"a\" < 2",
+			expected: "test:1:5: '<' can't compare string \"a\" with int 2",
+		},
+		{
+			input:    "[1] >= [0]",
+			expected: "test:1:5: '>=' can't compare list [1] with list [0]",
+		},
+	}
+	for _, test := range cases {
+		t.Run(test.input, func(t *testing.T) {
+			l := Lexer{In: strings.NewReader(test.input), File: "test"}
+			tkns, _ := l.Lex()
+			p := Parser{Tokens: tkns}
+			p.Parse()
+			i := NewInterpreter(&p.Lines, io.Discard)
+			_, err := i.Interpret()
+			var rerr *RuntimeError
+			if !errors.As(err, &rerr) {
+				t.Fatalf("expected a *RuntimeError, got %#v", err)
+			}
+			if err.Error() != test.expected {
+				t.Errorf("expected %v got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestGotoLabels(t *testing.T) {
+	input := `i = 0
+goto start
+skipped:
+print "never printed"
+start:
+i = i + 1
+print i
+if i < 3 goto start
+done: print " done"
+`
+	if expected, out := "123 done", runProgram(t, input); out != expected {
+		t.Errorf("expected %q got %q", expected, out)
+	}
+}
+
+func TestLoops(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "while",
+			input: `i = 0
+while i < 3
+i = i + 1
+print i
+end`,
+			expected: "123",
+		},
+		{
+			name: "for",
+			input: `for i = 1 to 3
+print i
+end
+print i`,
+			expected: "1233",
+		},
+		{
+			name: "for doesn't run backwards",
+			input: `for i = 3 to 1
+print i
+end`,
+			expected: "",
+		},
+		{
+			name: "for ignores changes to its variable",
+			input: `for i = 1 to 3
+print i
+i = 10
+end`,
+			expected: "123",
+		},
+		{
+			name: "for up to the biggest int",
+			input: `for i = 9223372036854775806 to 9223372036854775807
+print i + " "
+end`,
+			expected: "9223372036854775806 9223372036854775807 ",
+		},
+		{
+			name: "nested",
+			input: `for i = 1 to 2
+j = 0
+while j < i
+j = j + 1
+print ( i * 10 + j ) + " "
+end
+end`,
+			expected: "11 21 22 ",
+		},
+		{
+			name: "goto out of a loop",
+			input: `i = 0
+while 1
+i = i + 1
+if i == 3 goto done
+print i
+end
+done:
+print "done"`,
+			expected: "12done",
+		},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			if out := runProgram(t, test.input); out != test.expected {
+				t.Errorf("expected %q got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestIfBlocks(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "if",
+			input: `if 1 < 2
+print "a"
+print "b"
+end`,
+			expected: "ab",
+		},
+		{
+			name: "else",
+			input: `for i = 1 to 4
+if i % 2 == 0
+print "even "
+else
+print "odd "
+end
+end`,
+			expected: "odd even odd even ",
+		},
+		{
+			name: "empty else",
+			input: `if 0
+print "a"
+else
+end
+print "b"`,
+			expected: "b",
+		},
+		{
+			name: "nested",
+			input: `for i = 1 to 15
+if ( i % 3 != 0 ) & ( i % 5 != 0 )
+print i
+else
+if i % 3 == 0 print "fizz"
+if i % 5 == 0
+print "buzz"
+end
+end
+print " "
+end`,
+			expected: "1 2 fizz 4 buzz fizz 7 8 fizz buzz 11 fizz 13 14 fizzbuzz ",
+		},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			if out := runProgram(t, test.input); out != test.expected {
+				t.Errorf("expected %q got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "return",
+			input: `func add(a, b)
+return a + b
+end
+print add(1, add(2, 3))`,
+			expected: "6",
+		},
+		{
+			name: "called before it's defined",
+			input: `print twice("a")
+func twice(s)
+return s + s
+end`,
+			expected: "aa",
+		},
+		{
+			name: "recursion",
+			input: `func fib(n)
+if n < 2 return n
+return fib(n - 1) + fib(n - 2)
+end
+print fib(15)`,
+			expected: "610",
+		},
+		{
+			name: "locals",
+			input: `x = "global"
+func f(y)
+print x + " " + y + " "
+x = "local"
+print x + " "
+end
+f("arg")
+print x`,
+			expected: "global arg local global",
+		},
+		{
+			name: "return from inside a loop",
+			input: `func first(n)
+for i = 1 to 100
+if i * i > n
+return i
+end
+end
+end
+print first(50)`,
+			expected: "8",
+		},
+		{
+			name: "no return",
+			input: `func f()
+print "f "
+end
+print f()`,
+			expected: "f nil",
+		},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			if out := runProgram(t, test.input); out != test.expected {
+				t.Errorf("expected %q got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestLists(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "literals",
+			input:    `print [1, "a", [true, 2.5], []]`,
+			expected: `[1, "a", [true, 2.5], []]`,
+		},
+		{
+			name: "indexing",
+			input: `xs = [10, [20, 30]]
+print xs[0] + xs[1][1] + [1, 2][1]`,
+			expected: "42",
+		},
+		{
+			name: "index assignment",
+			input: `xs = [1, 2, 3]
+xs[1 + 1] = xs[0] * 10
+print xs`,
+			expected: "[1, 2, 10]",
+		},
+		{
+			name: "len, append and pop",
+			input: `xs = []
+for i = 1 to 3
+append(xs, i * i)
+end
+print len(xs) + " " + pop(xs) + " "
+print xs`,
+			expected: "3 9 [1, 4]",
+		},
+		{
+			name: "lists are shared",
+			input: `xs = [1]
+ys = xs
+ys[0] = 2
+print xs`,
+			expected: "[2]",
+		},
+		{
+			name: "lists inside themselves",
+			input: `xs = [1]
+append(xs, xs)
+ys = [1]
+append(ys, ys)
+print xs + " " + [xs, xs] + " " + ( xs == xs ) + " " + ( xs == ys ) + " " + ( xs == [1, [1]] )`,
+			expected: "[1, [...]] [[1, [...]], [1, [...]]] true true false",
+		},
+		{
+			name: "empty lists are false",
+			input: `if [] print "no"
+if [0] print "yes"`,
+			expected: "yes",
+		},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			if out := runProgram(t, test.input); out != test.expected {
+				t.Errorf("expected %q got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestMaps(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "literals",
+			input:    `print {"b": [1, "x"], "a": {"c": nil}, "": {}}`,
+			expected: `{"": {}, "a": {"c": nil}, "b": [1, "x"]}`,
+		},
+		{
+			name: "indexing",
+			input: `k = "a"
+m = {k: 1, "b" + "c": 2}
+m["d"] = m[k] + m["bc"]
+print m["d"] + " " + len(m)`,
+			expected: "3 3",
+		},
+		{
+			name: "keys, has and delete",
+			input: `m = {"x": 1, "y": 2}
+delete(m, "x")
+delete(m, "z")
+print keys(m) + " " + has(m, "x") + " " + has(m, "y")`,
+			expected: `["y"] false true`,
+		},
+		{
+			name: "maps are shared",
+			input: `m = {}
+n = m
+n["a"] = 1
+if m print m`,
+			expected: `{"a": 1}`,
+		},
+		{
+			name: "maps inside themselves",
+			input: `m = {"a": 1}
+m["self"] = m
+m["list"] = [m]
+n = {"a": 1}
+n["self"] = n
+n["list"] = [n]
+print m + " " + ( m == n ) + " " + ( m == {"a": 1} )`,
+			expected: `{"a": 1, "list": [{...}], "self": {...}} true false`,
+		},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			if out := runProgram(t, test.input); out != test.expected {
+				t.Errorf("expected %q got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestShortCircuit(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "guard",
+			input: `for i = 0 to 2
+print ( i != 0 ) & ( 10 / i > 1 )
+print " "
+end`,
+			expected: "false true true ",
+		},
+		{
+			name: "and skips the right side",
+			input: `func f(s)
+print s
+return 1
+end
+print 0 & f("no")
+print f("yes") & 0`,
+			expected: "falseyesfalse",
+		},
+		{
+			name: "or skips the right side",
+			input: `func f(s)
+print s
+return 0
+end
+print 1 | f("no")
+print f("yes") | "x"`,
+			expected: "trueyestrue",
+		},
+		{
+			name: "errors on the right side",
+			input: `xs = []
+if ( len(xs) > 0 ) & ( xs[0] == 1 ) print "no"
+if ( len(xs) == 0 ) | ( xs[0] == 1 ) print "yes"`,
+			expected: "yes",
+		},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			if out := runProgram(t, test.input); out != test.expected {
+				t.Errorf("expected %q got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	errBroken := errors.New("broken")
+	register := func(i *Interpreter) {
+		i.Register("double", 1, func(args []Value) (Value, error) {
+			return IntValue(args[0].Int() * 2), nil
+		})
+		i.Register("sum", -1, func(args []Value) (Value, error) {
+			sum := int64(0)
+			for _, arg := range args {
+				sum += arg.Int()
+			}
+			return IntValue(sum), nil
+		})
+		i.Register("first", 1, func(args []Value) (Value, error) {
+			return args[0].Elems()[0], nil
+		})
+		i.Register("len", 1, func(args []Value) (Value, error) {
+			return StringValue("host len"), nil
+		})
+		i.Register("broken", 0, func(args []Value) (Value, error) {
+			return Value{}, errBroken
+		})
+	}
+	cases := []struct {
+		input    string
+		expected string
+		err      string
+	}{
+		{input: `print double(sum(1, 2, 3)) + " " + sum()`, expected: "12 0"},
+		{input: `print first([double(4)])`, expected: "8"},
+		{input: `print len([])`, expected: "host len"},
+		{input: "func double(x)\nreturn x\nend\nprint double(3)", expected: "3"},
+		{input: `double(1, 2)`, err: "1:1: 'double' takes 1 arguments, got 2"},
+		{input: `print "a"
+broken()`, expected: "a", err: "2:1: 'broken' broken"},
+	}
+	for _, test := range cases {
+		t.Run(test.input, func(t *testing.T) {
+			out, err := run(t, test.input, register)
+			if out != test.expected {
+				t.Errorf("expected %q got %q", test.expected, out)
+			}
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case test.err != "" && (err == nil || err.Error() != test.err):
+				t.Errorf("expected error %v got %v", test.err, err)
+			}
+		})
+	}
+
+	i := Interpreter{}
+	register(&i)
+	l := Lexer{In: strings.NewReader("broken()")}
+	tkns, _ := l.Lex()
+	p := Parser{Tokens: tkns}
+	p.Parse()
+	i.Lines = &p.Lines
+	if _, err := i.Interpret(); !errors.Is(err, errBroken) {
+		t.Errorf("expected the error from the registered function, got %v", err)
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     int
+		expected string
+	}{
+		{name: "print", expected: `simpl: can't register "print", which isn't a name programs can call`},
+		{name: "if", expected: `simpl: can't register "if", which isn't a name programs can call`},
+		{name: "not", expected: `simpl: can't register "not", which isn't a name programs can call`},
+		{name: "true", expected: `simpl: can't register "true", which isn't a name programs can call`},
+		{name: "12", expected: `simpl: can't register "12", which isn't a name programs can call`},
+		{name: "l:", expected: `simpl: can't register "l:", which isn't a name programs can call`},
+		{name: "a-b", expected: `simpl: can't register "a-b", which isn't a name programs can call`},
+		{name: "", expected: `simpl: can't register "", which isn't a name programs can call`},
+		{name: "f", args: -2, expected: `simpl: can't register "f" taking -2 arguments`},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != test.expected {
+					t.Errorf("expected panic %q got %v", test.expected, r)
+				}
+			}()
+			i := Interpreter{}
+			i.Register(test.name, test.args, func(args []Value) (Value, error) {
+				return Value{}, nil
+			})
+		})
+	}
+}
+
+func ExampleInterpreter_Register() {
+	l := Lexer{In: strings.NewReader(`print greet("world")`)}
+	tkns, _ := l.Lex()
+	p := Parser{Tokens: tkns}
+	p.Parse()
+	i := NewInterpreter(&p.Lines, os.Stdout)
+	i.Register("greet", 1, func(args []Value) (Value, error) {
+		return StringValue("hello, " + args[0].String() + "!"), nil
+	})
+	i.Interpret()
+	// Output: hello, world!
+}
+
+// BenchmarkFizzbuzz runs fizzbuzz to 1,000,000, which jumps back with goto
+// for every number
+func BenchmarkFizzbuzz(b *testing.B) {
+	input := `i = 0
+loop:
+i = i + 1
+if i % 3 == 0 print "fizz"
+if i % 5 == 0 print "buzz"
+if ( i % 3 != 0 ) & ( i % 5 != 0 ) print i
+print "\n"
+if i < 1000000 goto loop
+`
+	l := Lexer{In: strings.NewReader(input)}
+	tkns, _ := l.Lex()
+	p := Parser{Tokens: tkns}
+	if errs := p.Parse(); len(errs) > 0 {
+		b.Fatalf("unexpected errors: %v", errs)
+	}
+	for _, bench := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "compiled"},
+		{name: "walked", opts: []Option{Walk()}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				i := NewInterpreter(&p.Lines, io.Discard, bench.opts...)
+				if _, err := i.Interpret(); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// run runs `input` with `opts`, and returns what it printed and its error
+func run(t *testing.T, input string, opts ...Option) (string, error) {
+	t.Helper()
+	l := Lexer{In: strings.NewReader(input)}
+	tkns, _ := l.Lex()
+	p := Parser{Tokens: tkns}
+	if errs := p.Parse(); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	out := strings.Builder{}
+	i := NewInterpreter(&p.Lines, &out, opts...)
+	_, err := i.Interpret()
+	return out.String(), err
+}
+
+// runProgram runs `input` with `opts` and returns what it printed
+func runProgram(t *testing.T, input string, opts ...Option) string {
+	t.Helper()
+	out, err := run(t, input, opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return out
+}
+
+// runError runs `input` with `opts`, which should fail with a *RuntimeError,
+// and returns the error
+func runError(t *testing.T, input string, opts ...Option) error {
+	t.Helper()
+	_, err := run(t, input, opts...)
+	var rerr *RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected a *RuntimeError, got %#v", err)
+	}
+	return err
+}
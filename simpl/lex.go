@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"log"
 	"strconv"
 )
 
@@ -23,21 +22,50 @@ const (
 	Var
 	Paren
 	Newline
+	Label
+	Comma
+	Call // made by the parser out of a variable followed by parentheses
+	Boolean
+	Bracket
+	ListLit // made by the parser out of a list literal
+	Index   // made by the parser out of something followed by brackets
+	Brace
+	Colon
+	MapLit // made by the parser out of a map literal
+	Unary  // also made by the parser out of a '-' in front of an operand
 )
 
 func (t TokenType) String() string {
-	return [...]string{"operator", "str", "num", "assignment", "boolop", "builtin", "keyword", "variable", "parenthesis", "newline"}[t]
+	return [...]string{"operator", "str", "num", "assignment", "boolop", "builtin", "keyword", "variable", "parenthesis", "newline", "label", "comma", "call", "boolean", "bracket", "list", "index", "brace", "colon", "map", "unary"}[t]
+}
+
+// Pos is a position in a source file
+type Pos struct {
+	File   string
+	Line   int // 1-based
+	Col    int // 1-based, counted in runes
+	Offset int // 0-based, counted in bytes
+}
+
+func (p Pos) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%v:%v", p.Line, p.Col)
+	}
+	return fmt.Sprintf("%v:%v:%v", p.File, p.Line, p.Col)
 }
 
 // Token holds information about a token
 type Token struct {
 	Class TokenType
 	Repr  string
+	Pos   Pos
 }
 
 // Lexer holds state needed for lexing
 type Lexer struct {
-	In io.Reader
+	In   io.Reader
+	File string // name used in the positions of tokens and errors
+	Line int    // line the input starts on, if it isn't the first
 }
 
 // Lex returns the lexed tokens in an io.Reader
@@ -47,102 +75,180 @@ func (l *Lexer) Lex() (tkns []Token, errors []error) {
 	quotes := 0
 	escape := false
 	comment := false
+	braces := 0 // how many braces are open, since ':' is a token inside them
+	cur := Pos{File: l.File, Line: 1, Col: 1}
+	if l.Line > 1 {
+		cur.Line = l.Line
+	}
+	start := cur // where `tkn` starts
+	// flush adds what's in `tkn` to `tkns`
+	flush := func() {
+		if len(tkn) > 0 && !comment {
+			// a '-' or '!' stuck to the front of something is a token by
+			// itself, unless the whole thing is a token like "-5" or "!="
+			for len(tkn) > 1 && (tkn[0] == '-' || tkn[0] == '!') {
+				if _, err := classifyToken(string(tkn)); err == nil {
+					break
+				}
+				t, _ := makeToken(string(tkn[:1]), start)
+				tkns = append(tkns, t)
+				tkn = tkn[1:]
+				start.Col++
+				start.Offset++
+			}
+			t, err := makeToken(string(tkn), start)
+			if err != nil {
+				errors = append(errors, err)
+			} else {
+				tkns = append(tkns, t)
+			}
+		}
+		tkn = []rune{}
+	}
 	for {
-		if c, _, err := input.ReadRune(); err != nil {
-			if err == io.EOF {
-				break
+		c, size, err := input.ReadRune()
+		if err != nil {
+			if err != io.EOF {
+				errors = append(errors, &LexError{Pos: cur, Err: err})
 			}
-			log.Fatal(err)
+			break
+		}
+		pos := cur
+		if c == '\n' {
+			cur.Line++
+			cur.Col = 1
 		} else {
-			if escape && !comment {
-				switch c {
-				case 'n':
-					tkn = append(tkn, '\n')
-				case 't':
-					tkn = append(tkn, '\t')
-				case '"':
-					tkn = append(tkn, '"')
-				case '\'':
-					tkn = append(tkn, '\'')
-				case '\\':
-					tkn = append(tkn, '\\')
-				default:
-					errors = append(errors, fmt.Errorf("unknown escape: %v", c))
+			cur.Col++
+		}
+		cur.Offset += size
+
+		if len(tkn) == 0 && quotes == 0 && !escape {
+			start = pos
+		}
+		if escape && !comment {
+			switch c {
+			case 'n':
+				tkn = append(tkn, '\n')
+			case 't':
+				tkn = append(tkn, '\t')
+			case '"':
+				tkn = append(tkn, '"')
+			case '\'':
+				tkn = append(tkn, '\'')
+			case '\\':
+				tkn = append(tkn, '\\')
+			default:
+				errors = append(errors, &LexError{Pos: pos, Err: fmt.Errorf("unknown escape: %v", c)})
+			}
+			escape = false
+			continue
+		}
+		switch c {
+		case '\\':
+			// found an escape character
+			escape = true
+		case ' ', '\t', '\r', '\n':
+			if quotes == 0 {
+				flush()
+				if c == '\n' {
+					comment = false
+					tkns = append(tkns, Token{Class: Newline, Repr: "\\n", Pos: pos})
 				}
-				escape = false
-				continue
-			} else {
+			} else if !comment {
+				tkn = append(tkn, c)
+			}
+		case '(', ')', '[', ']', '{', '}', ',', ':':
+			// these are tokens by themselves, even without spaces around them,
+			// except for a ':' outside of braces, which ends a label
+			switch {
+			case quotes > 0 || c == ':' && braces == 0:
+				if !comment {
+					tkn = append(tkn, c)
+				}
+			case !comment:
 				switch c {
-				case '\\':
-					// found an escape character
-					escape = true
-				case ' ', '\n':
-					if quotes == 0 {
-						if len(tkn) > 0 && !comment {
-							strTkn := string(tkn)
-							tkn = []rune{}
-							class, err := classifyToken(strTkn)
-							if err != nil {
-								errors = append(errors, err)
-								continue
-							}
-							tkns = append(tkns, Token{Class: class, Repr: strTkn})
-						}
-						if c == '\n' {
-							comment = false
-							tkns = append(tkns, Token{Class: Newline, Repr: "\\n"})
-						}
-					} else if !comment {
-						tkn = append(tkn, c)
-					}
-				case '"':
-					quotes++
-					if quotes == 2 && !comment {
-						strTkn := string(tkn)
-						tkn = []rune{}
-						if err != nil {
-							errors = append(errors, err)
-							continue
-						}
-						tkns = append(tkns, Token{Class: Str, Repr: strTkn})
-						quotes = 0
-					}
-				case '#':
-					comment = true
-				default:
-					if !comment {
-						tkn = append(tkn, c)
-					}
+				case '{':
+					braces++
+				case '}':
+					braces--
 				}
+				flush()
+				start = pos
+				tkn = append(tkn, c)
+				flush()
+			}
+		case '"':
+			if comment {
+				break
+			}
+			if quotes == 0 && len(tkn) > 0 {
+				// whatever's stuck to the front of a string isn't part of it
+				flush()
+				start = pos
+			}
+			quotes++
+			if quotes == 2 {
+				strTkn := string(tkn)
+				tkn = []rune{}
+				tkns = append(tkns, Token{Class: Str, Repr: strTkn, Pos: start})
+				quotes = 0
+			}
+		case '#':
+			if quotes == 0 {
+				flush()
+				comment = true
+			} else if !comment {
+				tkn = append(tkn, c)
+			}
+		default:
+			if !comment {
+				tkn = append(tkn, c)
 			}
 		}
 	}
-	if len(tkn) > 0 && !comment {
-		strTkn := string(tkn)
-		class, err := classifyToken(strTkn)
-		if err != nil {
-			errors = append(errors, err)
-		}
-		tkns = append(tkns, Token{Class: class, Repr: strTkn})
-	}
+	flush()
 
 	return tkns, errors
 }
 
+// makeToken classifies `t` and makes a token out of it
+func makeToken(t string, pos Pos) (Token, error) {
+	class, err := classifyToken(t)
+	if err != nil {
+		return Token{}, &LexError{Pos: pos, Err: err}
+	}
+	if class == Label {
+		t = t[:len(t)-1]
+	}
+	return Token{Class: class, Repr: t, Pos: pos}, nil
+}
+
 func classifyToken(t string) (TokenType, error) {
 	switch t {
 	case "+", "-", "*", "/", "%":
 		return Operator, nil
-	case "<", ">", "==", "&", "|", "!=":
+	case "<", ">", "<=", ">=", "==", "&", "|", "!=":
 		return Boolop, nil
+	case "!", "not":
+		return Unary, nil
 	case "print", "goto":
 		return Builtin, nil
-	case "if":
+	case "if", "else", "while", "for", "to", "end", "func", "return":
 		return Keyword, nil
 	case "=":
 		return Assignment, nil
 	case "(", ")":
 		return Paren, nil
+	case "[", "]":
+		return Bracket, nil
+	case "{", "}":
+		return Brace, nil
+	case ":":
+		return Colon, nil
+	case ",":
+		return Comma, nil
+	case "true", "false":
+		return Boolean, nil
 	}
 	if t[0] == '"' && t[len(t)-1] == '"' {
 		return Str, nil
@@ -151,6 +257,9 @@ func classifyToken(t string) (TokenType, error) {
 	if err == nil {
 		return Num, nil
 	}
+	if len(t) > 1 && t[len(t)-1] == ':' && isAlphaNumeric(t[:len(t)-1]) {
+		return Label, nil
+	}
 	// if it's not anything else, it's probably an ident
 	if isAlphaNumeric(t) {
 		return Var, nil
@@ -59,6 +59,70 @@ if i < 100 goto 2
 				Token{Class: Num, Repr: "0"},
 			},
 		},
+		{
+			name:  "label",
+			input: "loop: goto loop",
+			expected: []Token{
+				Token{Class: Label, Repr: "loop"},
+				Token{Class: Builtin, Repr: "goto"},
+				Token{Class: Var, Repr: "loop"},
+			},
+		},
+		{
+			name:  "call",
+			input: "x = add(1,( 2 + 3 ))\tprint \"a(b, c)\" # f(x)",
+			expected: []Token{
+				Token{Class: Var, Repr: "x"},
+				Token{Class: Assignment, Repr: "="},
+				Token{Class: Var, Repr: "add"},
+				Token{Class: Paren, Repr: "("},
+				Token{Class: Num, Repr: "1"},
+				Token{Class: Comma, Repr: ","},
+				Token{Class: Paren, Repr: "("},
+				Token{Class: Num, Repr: "2"},
+				Token{Class: Operator, Repr: "+"},
+				Token{Class: Num, Repr: "3"},
+				Token{Class: Paren, Repr: ")"},
+				Token{Class: Paren, Repr: ")"},
+				Token{Class: Builtin, Repr: "print"},
+				Token{Class: Str, Repr: "a(b, c)"},
+			},
+		},
+		{
+			name:  "map",
+			input: `top: m = {"a":1, b: [2]}`,
+			expected: []Token{
+				Token{Class: Label, Repr: "top"},
+				Token{Class: Var, Repr: "m"},
+				Token{Class: Assignment, Repr: "="},
+				Token{Class: Brace, Repr: "{"},
+				Token{Class: Str, Repr: "a"},
+				Token{Class: Colon, Repr: ":"},
+				Token{Class: Num, Repr: "1"},
+				Token{Class: Comma, Repr: ","},
+				Token{Class: Var, Repr: "b"},
+				Token{Class: Colon, Repr: ":"},
+				Token{Class: Bracket, Repr: "["},
+				Token{Class: Num, Repr: "2"},
+				Token{Class: Bracket, Repr: "]"},
+				Token{Class: Brace, Repr: "}"},
+			},
+		},
+		{
+			name:  "unary",
+			input: `-x - -1 !y != !"z"`,
+			expected: []Token{
+				Token{Class: Operator, Repr: "-"},
+				Token{Class: Var, Repr: "x"},
+				Token{Class: Operator, Repr: "-"},
+				Token{Class: Num, Repr: "-1"},
+				Token{Class: Unary, Repr: "!"},
+				Token{Class: Var, Repr: "y"},
+				Token{Class: Boolop, Repr: "!="},
+				Token{Class: Unary, Repr: "!"},
+				Token{Class: Str, Repr: "z"},
+			},
+		},
 		{
 			name:  "parenthesis",
 			input: "( ) ( )",
@@ -85,7 +149,7 @@ if i < 100 goto 2
 					t.Errorf("expected %v, got %v", test.errors[i], e)
 				}
 			}
-			for i, r := range results {
+			for i, r := range withoutPos(results) {
 				if !reflect.DeepEqual(r, test.expected[i]) {
 					t.Errorf("expected %v, got %v", test.expected[i], r)
 				}
@@ -94,6 +158,59 @@ if i < 100 goto 2
 	}
 }
 
+func TestLexPositions(t *testing.T) {
+	input := `i = "a b"
+# a comment
+  print i # another
+j = 2 + ??`
+	expected := []Token{
+		Token{Class: Var, Repr: "i", Pos: Pos{File: "test", Line: 1, Col: 1, Offset: 0}},
+		Token{Class: Assignment, Repr: "=", Pos: Pos{File: "test", Line: 1, Col: 3, Offset: 2}},
+		Token{Class: Str, Repr: "a b", Pos: Pos{File: "test", Line: 1, Col: 5, Offset: 4}},
+		Token{Class: Newline, Repr: "\\n", Pos: Pos{File: "test", Line: 1, Col: 10, Offset: 9}},
+		Token{Class: Newline, Repr: "\\n", Pos: Pos{File: "test", Line: 2, Col: 12, Offset: 21}},
+		Token{Class: Builtin, Repr: "print", Pos: Pos{File: "test", Line: 3, Col: 3, Offset: 24}},
+		Token{Class: Var, Repr: "i", Pos: Pos{File: "test", Line: 3, Col: 9, Offset: 30}},
+		Token{Class: Newline, Repr: "\\n", Pos: Pos{File: "test", Line: 3, Col: 20, Offset: 41}},
+		Token{Class: Var, Repr: "j", Pos: Pos{File: "test", Line: 4, Col: 1, Offset: 42}},
+		Token{Class: Assignment, Repr: "=", Pos: Pos{File: "test", Line: 4, Col: 3, Offset: 44}},
+		Token{Class: Num, Repr: "2", Pos: Pos{File: "test", Line: 4, Col: 5, Offset: 46}},
+		Token{Class: Operator, Repr: "+", Pos: Pos{File: "test", Line: 4, Col: 7, Offset: 48}},
+	}
+	l := Lexer{In: strings.NewReader(input), File: "test"}
+	results, errs := l.Lex()
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %v,\ngot      %v", expected, results)
+	}
+	expectedErr := "test:4:9: unrecognized token: '??'"
+	if len(errs) != 1 || errs[0].Error() != expectedErr {
+		t.Fatalf("expected error %v, got %v", expectedErr, errs)
+	}
+	if _, ok := errs[0].(*LexError); !ok {
+		t.Errorf("expected a *LexError, got %#v", errs[0])
+	}
+
+	l = Lexer{In: strings.NewReader("x\n??"), File: "<stdin>", Line: 7}
+	results, errs = l.Lex()
+	if pos := results[0].Pos; pos != (Pos{File: "<stdin>", Line: 7, Col: 1}) {
+		t.Errorf("expected the input to start on line 7, got %v", pos)
+	}
+	expectedErr = "<stdin>:8:1: unrecognized token: '??'"
+	if len(errs) != 1 || errs[0].Error() != expectedErr {
+		t.Errorf("expected error %v, got %v", expectedErr, errs)
+	}
+}
+
+// withoutPos returns a copy of `tkns` with their positions zeroed
+func withoutPos(tkns []Token) []Token {
+	stripped := make([]Token, len(tkns))
+	for i, tkn := range tkns {
+		tkn.Pos = Pos{}
+		stripped[i] = tkn
+	}
+	return stripped
+}
+
 func TestClassifyToken(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -150,6 +267,36 @@ func TestClassifyToken(t *testing.T) {
 			expected: Boolop,
 			err:      nil,
 		},
+		{
+			input:    "true",
+			expected: Boolean,
+			err:      nil,
+		},
+		{
+			input:    "false",
+			expected: Boolean,
+			err:      nil,
+		},
+		{
+			input:    "<=",
+			expected: Boolop,
+			err:      nil,
+		},
+		{
+			input:    ">=",
+			expected: Boolop,
+			err:      nil,
+		},
+		{
+			input:    "!",
+			expected: Unary,
+			err:      nil,
+		},
+		{
+			input:    "not",
+			expected: Unary,
+			err:      nil,
+		},
 		{
 			input:    "##|",
 			expected: 0,
@@ -215,6 +362,31 @@ func TestClassifyToken(t *testing.T) {
 			expected: Boolop,
 			err:      nil,
 		},
+		{
+			input:    ",",
+			expected: Comma,
+			err:      nil,
+		},
+		{
+			input:    "func",
+			expected: Keyword,
+			err:      nil,
+		},
+		{
+			input:    "loop:",
+			expected: Label,
+			err:      nil,
+		},
+		{
+			input:    ":",
+			expected: Colon,
+			err:      nil,
+		},
+		{
+			input:    "::",
+			expected: 0,
+			err:      fmt.Errorf("unrecognized token: '%v'", "::"),
+		},
 	}
 
 	for _, test := range tests {
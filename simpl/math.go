@@ -0,0 +1,173 @@
+package simpl
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// builtinAbs returns the absolute value of a number
+func builtinAbs(in *Interpreter, args []Value) (Value, error) {
+	n, err := args[0].number()
+	if err != nil {
+		return Value{}, err
+	}
+	if n.Kind == Int {
+		if n.i < 0 {
+			return IntValue(-n.i), nil
+		}
+		return n, nil
+	}
+	return FloatValue(math.Abs(n.f)), nil
+}
+
+// builtinFloor rounds a number down to an int
+func builtinFloor(in *Interpreter, args []Value) (Value, error) {
+	return toInt(args[0], math.Floor)
+}
+
+// builtinRound rounds a number to the nearest int, rounding halves away from
+// zero
+func builtinRound(in *Interpreter, args []Value) (Value, error) {
+	return toInt(args[0], math.Round)
+}
+
+// toInt turns the number `v` into an Int, using `round` to round Floats
+func toInt(v Value, round func(float64) float64) (Value, error) {
+	n, err := v.number()
+	if err != nil {
+		return Value{}, err
+	}
+	if n.Kind == Int {
+		return n, nil
+	}
+	f := round(n.f)
+	if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return Value{}, fmt.Errorf("%v can't be made into an int", n)
+	}
+	return IntValue(int64(f)), nil
+}
+
+// builtinSqrt returns the square root of a number
+func builtinSqrt(in *Interpreter, args []Value) (Value, error) {
+	n, err := args[0].number()
+	if err != nil {
+		return Value{}, err
+	}
+	if n.Float() < 0 {
+		return Value{}, fmt.Errorf("can't take the square root of %v", n)
+	}
+	return FloatValue(math.Sqrt(n.Float())), nil
+}
+
+// builtinPow raises a number to a power. It's an Int if they're both Ints,
+// the power isn't negative, and the result fits in an Int.
+func builtinPow(in *Interpreter, args []Value) (Value, error) {
+	x, err := args[0].number()
+	if err != nil {
+		return Value{}, err
+	}
+	y, err := args[1].number()
+	if err != nil {
+		return Value{}, err
+	}
+	if x.Kind == Int && y.Kind == Int && y.i >= 0 {
+		if result, ok := intPow(x.i, y.i); ok {
+			return IntValue(result), nil
+		}
+	}
+	return FloatValue(math.Pow(x.Float(), y.Float())), nil
+}
+
+// intPow returns `x` to the power of `y`, and whether it fits in an int64
+func intPow(x, y int64) (int64, bool) {
+	result := int64(1)
+	for base, exp := x, y; exp > 0; exp >>= 1 {
+		ok := true
+		if exp&1 == 1 {
+			if result, ok = multiply(result, base); !ok {
+				return 0, false
+			}
+		}
+		if exp > 1 {
+			if base, ok = multiply(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+// multiply returns `a` times `b`, and whether it fits in an int64
+func multiply(a, b int64) (int64, bool) {
+	product := a * b
+	if a != 0 && (product/a != b || a == -1 && b == math.MinInt64) {
+		return 0, false
+	}
+	return product, true
+}
+
+// builtinMin returns the smallest of some numbers, or of a list of them
+func builtinMin(in *Interpreter, args []Value) (Value, error) {
+	return pick(args, func(a, b Value) bool { return a.Float() < b.Float() })
+}
+
+// builtinMax returns the biggest of some numbers, or of a list of them
+func builtinMax(in *Interpreter, args []Value) (Value, error) {
+	return pick(args, func(a, b Value) bool { return a.Float() > b.Float() })
+}
+
+// pick returns the number in `args`, or in the list that's the only thing in
+// `args`, that's `better` than all the others
+func pick(args []Value, better func(a, b Value) bool) (Value, error) {
+	if len(args) == 1 && args[0].Kind == List {
+		args = *args[0].l
+		if len(args) == 0 {
+			return Value{}, fmt.Errorf("got an empty list")
+		}
+	}
+	var best Value
+	for i, arg := range args {
+		n, err := arg.number()
+		if err != nil {
+			return Value{}, err
+		}
+		if i == 0 || better(n, best) {
+			best = n
+		}
+	}
+	return best, nil
+}
+
+// builtinRandom returns a random Float from 0 up to 1 with no arguments, a
+// random Int from 0 up to `n` with one, and a random Int from `a` up to `b`
+// with two. The starts of the ranges can be returned, but the ends never are.
+func builtinRandom(in *Interpreter, args []Value) (Value, error) {
+	if in.rand == nil {
+		in.Seed(time.Now().UnixNano())
+	}
+	if len(args) == 0 {
+		return FloatValue(in.rand.Float64()), nil
+	}
+	bounds := make([]int64, len(args))
+	for i, arg := range args {
+		n, err := arg.integer()
+		if err != nil {
+			return Value{}, err
+		}
+		bounds[i] = int64(n)
+	}
+	lo, hi := int64(0), bounds[0]
+	if len(bounds) == 2 {
+		lo, hi = bounds[0], bounds[1]
+	}
+	if lo >= hi {
+		return Value{}, fmt.Errorf("there are no ints from %v up to %v", lo, hi)
+	}
+	// the number of ints in the range overflows if it's more than an int can hold
+	width := hi - lo
+	if width <= 0 {
+		return Value{}, fmt.Errorf("the range from %v up to %v is too wide", lo, hi)
+	}
+	return IntValue(lo + in.rand.Int63n(width)), nil
+}
@@ -0,0 +1,76 @@
+package simpl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMathBuiltins(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{input: `print abs(-3) + " " + abs(2.5) + " " + abs(-0.5)`, expected: "3 2.5 0.5"},
+		{input: `print floor(2.7) + " " + floor(-2.5) + " " + floor(4)`, expected: "2 -3 4"},
+		{input: `print round(2.5) + " " + round(-2.5) + " " + round(2.4)`, expected: "3 -3 2"},
+		{input: `xs = [1, 2, 3]
+print xs[floor(5 / 2)]`, expected: "3"},
+		{input: `print sqrt(16) + " " + sqrt(2)`, expected: "4 1.4142135623730951"},
+		{input: `print pow(2, 10) + " " + pow(2, -1) + " " + pow(4, 0.5) + " " + pow(7, 0)`, expected: "1024 0.5 2 1"},
+		{input: `print pow(2, 62) + " " + pow(-2, 63) + " " + pow(2, 63) + " " + pow(2, 1000) + " " + pow(-1, 1001) + " " + pow(0, 1000)`, expected: "4611686018427387904 -9223372036854775808 9.223372036854776e+18 1.0715086071862673e+301 -1 0"},
+		{input: `print min(3, 1.5, 2) + " " + max(3, 1.5, 2) + " " + min([4, -1]) + " " + max(7)`, expected: "1.5 3 -1 7"},
+	}
+	for _, test := range cases {
+		t.Run(test.input, func(t *testing.T) {
+			if out := runProgram(t, test.input); out != test.expected {
+				t.Errorf("expected %q got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestMathBuiltinErrors(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{input: `sqrt(-1)`, expected: "1:1: 'sqrt' can't take the square root of -1"},
+		{input: `abs("a")`, expected: "1:1: 'abs' expected a number, got string 'a'"},
+		{input: `floor(1e100)`, expected: "1:1: 'floor' 1e+100 can't be made into an int"},
+		{input: `max([])`, expected: "1:1: 'max' got an empty list"},
+		{input: `min()`, expected: "1:1: 'min' takes at least 1 arguments, got 0"},
+		{input: `random(5, 5)`, expected: "1:1: 'random' there are no ints from 5 up to 5"},
+		{input: `random(1.5)`, expected: "1:1: 'random' expected an int, got float '1.5'"},
+		{input: `random(0 - 9000000000000000000, 9000000000000000000)`, expected: "1:1: 'random' the range from -9000000000000000000 up to 9000000000000000000 is too wide"},
+	}
+	for _, test := range cases {
+		t.Run(test.input, func(t *testing.T) {
+			if err := runError(t, test.input); err.Error() != test.expected {
+				t.Errorf("expected %v got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestRandom(t *testing.T) {
+	input := `for i = 1 to 20
+x = random(3, 6)
+if ( x < 3 ) | ( x >= 6 ) print "bad "
+y = random()
+if ( y < 0 ) | ( y >= 1 ) print "bad "
+print x + " " + random(10) + " "
+end`
+	seeded := func(seed int64) string {
+		return runProgram(t, input, func(i *Interpreter) { i.Seed(seed) })
+	}
+	first := seeded(42)
+	if strings.Contains(first, "bad") {
+		t.Errorf("random number out of range: %q", first)
+	}
+	if again := seeded(42); again != first {
+		t.Errorf("expected the same numbers for the same seed, got %q and %q", first, again)
+	}
+	if other := seeded(43); other == first {
+		t.Errorf("expected different numbers for a different seed, got %q", other)
+	}
+}
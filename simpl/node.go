@@ -1,49 +1,132 @@
 package simpl
 
-import "fmt"
+import "strings"
 
 // Node is a node of an abstract syntax tree
 type Node struct {
 	left  *Node
 	right *Node
 	val   Token
+	body  []*Node // the lines inside a block
+	alt   []*Node // the lines after the `else` in an `if` block
+	args  []*Node // the arguments of a call, or the elements of a list or map
+	num   Value   // the value of a Num node, once it's been parsed
 }
 
-// height gets the height of a given tree with root node `n`
-func (n *Node) height() int {
-	lheight := 0
-	rheight := 0
+// Pos returns the position in the source of the token the node was made from
+func (n *Node) Pos() Pos {
+	return n.val.Pos
+}
+
+// Token returns the token the node was made from
+func (n *Node) Token() Token {
+	return n.val
+}
+
+// Left returns the left operand of the node
+func (n *Node) Left() *Node {
+	return n.left
+}
+
+// Right returns the right operand of the node
+func (n *Node) Right() *Node {
+	return n.right
+}
+
+// Body returns the lines inside the node's block
+func (n *Node) Body() []*Node {
+	return n.body
+}
+
+// Alt returns the lines after the `else` of an `if` block
+func (n *Node) Alt() []*Node {
+	return n.alt
+}
+
+// Args returns the arguments of a call, or the elements of a list or map
+func (n *Node) Args() []*Node {
+	return n.args
+}
+
+// Map returns a copy of the node with `fn` applied to its operands, its
+// arguments, and the lines of its blocks. Lines that `fn` returns nil for are
+// dropped from the blocks.
+func (n *Node) Map(fn func(*Node) *Node) *Node {
+	c := *n
 	if n.left != nil {
-		lheight = n.left.height()
+		c.left = fn(n.left)
 	}
 	if n.right != nil {
-		rheight = n.right.height()
+		c.right = fn(n.right)
 	}
-	if lheight > rheight {
-		return lheight + 1
-	}
-	return rheight + 1
+	c.args = mapNodes(n.args, fn, false)
+	c.body = mapNodes(n.body, fn, true)
+	c.alt = mapNodes(n.alt, fn, true)
+	return &c
 }
 
-// PrintTree prints the tree in breadth-first order
-func (n *Node) PrintTree() {
-	h := n.height()
-	for i := 1; i < h+1; i++ {
-		n.printLevel(i, "")
-		fmt.Println()
+// mapNodes applies `fn` to `nodes`, dropping the ones it returns nil for if
+// they're `lines`
+func mapNodes(nodes []*Node, fn func(*Node) *Node, lines bool) []*Node {
+	if nodes == nil {
+		return nil
+	}
+	mapped := make([]*Node, 0, len(nodes))
+	for _, n := range nodes {
+		m := fn(n)
+		if m == nil && lines {
+			continue
+		}
+		mapped = append(mapped, m)
 	}
+	return mapped
 }
 
-// printLevel prints the data at a given level of a tree
-func (n *Node) printLevel(level int, side string) {
-	if level == 1 {
-		fmt.Printf("%v %v ", side, n.val)
-	} else if level > 1 {
-		if n.left != nil {
-			n.left.printLevel(level-1, side+":L")
-		}
-		if n.right != nil {
-			n.right.printLevel(level-1, side+":R")
+// WithOperands returns a copy of the node with the operands `left` and `right`
+func (n *Node) WithOperands(left, right *Node) *Node {
+	c := *n
+	c.left, c.right = left, right
+	return &c
+}
+
+// Literal makes a node for the number, string, or bool `v` at `pos`, which is
+// what the node would be if `v` had been written in the program
+func Literal(v Value, pos Pos) *Node {
+	switch v.Kind {
+	case Int, Float:
+		repr := v.String()
+		if v.Kind == Float && !strings.ContainsAny(repr, ".eIN") {
+			repr += ".0"
 		}
+		return &Node{val: Token{Class: Num, Repr: repr, Pos: pos}, num: v}
+	case Bool:
+		return &Node{val: Token{Class: Boolean, Repr: v.String(), Pos: pos}}
+	}
+	return &Node{val: Token{Class: Str, Repr: v.String(), Pos: pos}}
+}
+
+// Constant returns the value of the node if it's a number, string, or bool
+// written in the program
+func (n *Node) Constant() (Value, bool) {
+	if n == nil {
+		return Value{}, false
+	}
+	switch n.val.Class {
+	case Num:
+		v, err := n.number()
+		return v, err == nil
+	case Str:
+		return StringValue(n.val.Repr), true
+	case Boolean:
+		return BoolValue(n.val.Repr == "true"), true
+	}
+	return Value{}, false
+}
+
+// number returns the value of a Num node
+func (n *Node) number() (Value, error) {
+	if n.num.Kind != Nil {
+		return n.num, nil
 	}
+	return parseNumber(n.val.Repr)
 }
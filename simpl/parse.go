@@ -1,51 +1,340 @@
 package simpl
 
+import "fmt"
+
 // Parser holds the state needed for parsing
 type Parser struct {
 	Tokens      []Token
 	Lines       []*Node
+	Incremental bool // whether blocks can be finished by a later call to Parse
 	operators   Stack
 	operands    Stack
 	assignments Stack
 	keywords    Stack
+	labels      map[string]bool // labels of the lines in `Lines`
+	blocks      Stack           // blocks that haven't been closed with `end` yet
+	broken      map[*Node]bool  // blocks with errors in their first line
+	first       *Node           // the first token on this line
+	last        *Node           // the token before the current one on this line
+	builtin     bool            // whether there's a builtin on this line
+	bare        *Node           // a `return` with nothing after it
+	errors      []error
+	lineErrors  int // len(errors) at the start of the current line
 }
 
-// Parse parses the tokens into an AST
-func (p *Parser) Parse() {
+// Parse parses the tokens into an AST. Lines with errors in them aren't added
+// to `Lines`, and neither are blocks until they're closed with `end`. Blocks
+// that are still open at the end of `Tokens`, and gotos to labels that aren't
+// defined, are errors, unless `Incremental` is set, since later calls could
+// finish or define them.
+func (p *Parser) Parse() (errors []error) {
+	start := len(p.Lines)
+	p.errors = nil
+	p.lineErrors = 0
 	for _, tkn := range p.Tokens {
 		t := &Node{val: tkn}
+		prev := p.last
+		if tkn.Class == Operator && tkn.Repr == "-" && (prev == nil || !endsOperand(prev)) {
+			// there's nothing on the left to subtract from, so it's a negation
+			t.val.Class = Unary
+		}
+		p.check(t)
 		switch tkn.Class {
-		case Str, Num, Var:
+		case Str, Num, Boolean, Var:
 			p.operands.Push(t)
 		case Assignment:
 			p.handleToken(t, &p.assignments)
-		case Boolop, Operator, Builtin:
+		case Boolop, Operator, Builtin, Unary:
 			p.handleToken(t, &p.operators)
-		case Keyword:
+		case Keyword, Label:
+			// `to` is part of the expression in a `for`, and `return` goes
+			// with the other statements that can be the body of an `if`
+			if isKeyword(t, "to") || isKeyword(t, "return") {
+				p.handleToken(t, &p.operators)
+				continue
+			}
 			p.handleToken(t, &p.keywords)
 		case Paren:
 			switch tkn.Repr {
 			case "(":
+				if prev != nil && prev.val.Class == Var {
+					// a call: the paren holds on to it until it's closed
+					call := p.operands.Pop()
+					call.val.Class = Call
+					t.left = call
+				}
 				p.operators.Push(t)
 			case ")":
-				for p.operators.Peek().val.Repr != "(" {
-					op := p.operators.Pop()
-					if p.operands.Peek() != nil {
-						op.right = p.operands.Pop()
-					}
-					if op.val.Class != Builtin { // builtins only have a right side
-						if p.operands.Peek() != nil {
-							op.left = p.operands.Pop()
-						}
-					}
-					p.operands.Push(op)
+				p.closeParen(prev, "(")
+			}
+		case Bracket:
+			switch tkn.Repr {
+			case "[":
+				// the bracket holds on to the list or index until it's closed
+				if prev != nil && endsOperand(prev) {
+					t.left = &Node{val: Token{Class: Index, Repr: "[]", Pos: tkn.Pos}, left: p.operands.Pop()}
+				} else {
+					t.left = &Node{val: Token{Class: ListLit, Repr: "[]", Pos: tkn.Pos}}
 				}
+				p.operators.Push(t)
+			case "]":
+				p.closeParen(prev, "[")
+			}
+		case Brace:
+			switch tkn.Repr {
+			case "{":
+				t.left = &Node{val: Token{Class: MapLit, Repr: "{}", Pos: tkn.Pos}}
+				p.operators.Push(t)
+			case "}":
+				p.closeParen(prev, "{")
+			}
+		case Colon:
+			p.handleToken(t, &p.operators)
+		case Comma:
+			if p.innermostParen() != nil {
+				p.closeArg(false)
 			}
 		case Newline:
 			p.emptyStacks()
 		}
 	}
+	p.check(nil)
 	p.emptyStacks()
+	if !p.Incremental {
+		for !p.blocks.IsEmpty() {
+			b := p.blocks.Pop()
+			p.errorf(b, "'%v' isn't closed with 'end'", b.val.Repr)
+		}
+		for _, line := range p.Lines[start:] {
+			p.checkLabels(line)
+		}
+	}
+	return p.errors
+}
+
+// checkLabels makes sure that every goto to a label in `n` goes to a label
+// in `Lines`
+func (p *Parser) checkLabels(n *Node) {
+	if n == nil {
+		return
+	}
+	if n.val.Class == Builtin && n.val.Repr == "goto" && n.right != nil && n.right.val.Class == Var && !p.labels[n.right.val.Repr] {
+		p.errorf(n.right, "unknown label '%v'", n.right.val.Repr)
+	}
+	p.checkLabels(n.left)
+	p.checkLabels(n.right)
+	for _, lines := range [][]*Node{n.body, n.alt} {
+		for _, line := range lines {
+			p.checkLabels(line)
+		}
+	}
+}
+
+// closeParen closes the innermost parentheses, brackets, or braces, which are
+// opened with `open`, at the token after `prev`
+func (p *Parser) closeParen(prev *Node, open string) {
+	if o := p.innermostParen(); o == nil || o.val.Repr != open {
+		return
+	}
+	o := p.closeArg(prev != nil && prev.val.Repr == open)
+	p.operators.Pop()
+	if o.left != nil {
+		p.operands.Push(o.left)
+	}
+}
+
+// closeArg levels the operators inside the innermost parentheses, brackets, or
+// braces. If they belong to a call, list, or map, it gives it its next
+// argument, element, or entry (unless they're `empty`), and if they're the
+// brackets of an index it gives the index the thing inside them. It returns
+// the open paren, bracket, or brace.
+func (p *Parser) closeArg(empty bool) *Node {
+	for !isOpen(p.operators.Peek()) {
+		p.levelStack(&p.operators)
+	}
+	open := p.operators.Peek()
+	n := open.left
+	if n == nil || empty || !p.lineOK() {
+		return open
+	}
+	arg := p.operands.Pop()
+	switch {
+	case n.val.Class == Index:
+		n.right = arg
+	case n.val.Class == MapLit && arg.val.Class != Colon:
+		p.errorf(arg, "expected '<key>: <value>'")
+	case n.val.Class == MapLit && arg.right.val.Class == Colon:
+		p.errorf(arg.right, "expected '<key>: <value>'")
+	default:
+		n.args = append(n.args, arg)
+	}
+	return open
+}
+
+// InBlock returns whether there's a block that hasn't been closed yet
+func (p *Parser) InBlock() bool {
+	return !p.blocks.IsEmpty()
+}
+
+// check makes sure `t` can come after the token before it on the line. `t` is
+// nil at the end of the input.
+func (p *Parser) check(t *Node) {
+	last := p.last
+	p.last = t
+	if last == nil {
+		p.first = t
+	}
+	if t != nil && t.val.Class == Builtin {
+		p.builtin = true
+	}
+	if t != nil && t.val.Class == Newline {
+		p.last = nil
+	}
+	// whether `last` leaves us waiting for an operand
+	wantOperand := last == nil
+	if last != nil {
+		switch last.val.Class {
+		case Operator, Boolop, Assignment, Builtin, Keyword, Label, Comma, Colon, Unary:
+			wantOperand = !alone(last)
+		case Paren, Bracket, Brace:
+			wantOperand = isOpen(last)
+		}
+	}
+
+	if t == nil || t.val.Class == Newline {
+		switch {
+		case last == nil || !wantOperand || last.val.Class == Label:
+		case isKeyword(last, "return"):
+			p.bare = last
+		default:
+			p.errorf(last, "'%v' is missing an operand", last.val.Repr)
+		}
+		return
+	}
+	if last != nil && alone(last) {
+		p.errorf(t, "expected a new line after '%v'", last.val.Repr)
+		return
+	}
+	switch t.val.Class {
+	case Keyword:
+		switch t.val.Repr {
+		case "to":
+			if wantOperand {
+				p.errorf(t, "'to' is missing an operand")
+			}
+		case "while", "for", "else", "end", "func":
+			if last != nil {
+				p.errorf(t, "'%v' has to be at the start of a line", t.val.Repr)
+			}
+		case "if":
+			// an `if` can come after a label, which runs it
+			if last != nil && last.val.Class != Label {
+				p.errorf(t, "'if' has to be at the start of a line")
+			}
+		}
+	case Label:
+		if last != nil {
+			p.errorf(t, "label '%v' has to be at the start of a line", t.val.Repr)
+		} else if p.labels[t.val.Repr] {
+			p.errorf(t, "duplicate label '%v'", t.val.Repr)
+		}
+	case Str, Num, Boolean, Var, Unary:
+		if !wantOperand {
+			p.errorf(t, "expected an operator before '%v'", t.val.Repr)
+		}
+	case Operator, Boolop, Assignment:
+		if wantOperand {
+			p.errorf(t, "'%v' is missing an operand", t.val.Repr)
+		}
+	case Paren:
+		switch t.val.Repr {
+		case "(":
+			if !wantOperand && last.val.Class != Var {
+				p.errorf(t, "expected an operator before '('")
+			}
+		case ")":
+			switch {
+			case !p.closes(t):
+			case last.val.Repr == "(" && last.left == nil:
+				p.errorf(t, "empty parentheses")
+			case last.val.Repr == "(":
+			case wantOperand:
+				p.errorf(last, "'%v' is missing an operand", last.val.Repr)
+			}
+		}
+	case Bracket:
+		if t.val.Repr == "]" {
+			switch {
+			case !p.closes(t):
+			case last.val.Repr == "[" && last.left.val.Class == Index:
+				p.errorf(t, "expected an index between '[' and ']'")
+			case last.val.Repr == "[":
+			case wantOperand:
+				p.errorf(last, "'%v' is missing an operand", last.val.Repr)
+			}
+		}
+	case Brace:
+		switch t.val.Repr {
+		case "{":
+			if !wantOperand {
+				p.errorf(t, "expected an operator before '{'")
+			}
+		case "}":
+			switch {
+			case !p.closes(t):
+			case last.val.Repr == "{":
+			case wantOperand:
+				p.errorf(last, "'%v' is missing an operand", last.val.Repr)
+			}
+		}
+	case Colon:
+		if open := p.innermostParen(); open == nil || open.val.Repr != "{" {
+			p.errorf(t, "':' can only separate the keys and values of a map")
+		} else if wantOperand {
+			p.errorf(t, "expected a key before ':'")
+		}
+	case Comma:
+		if open := p.innermostParen(); open == nil || open.left == nil || open.left.val.Class == Index {
+			p.errorf(t, "',' can only separate the arguments of a call, or the elements of a list or map")
+		} else if wantOperand {
+			p.errorf(t, "expected an argument before ','")
+		}
+	}
+}
+
+// closes makes sure the closing paren, bracket, or brace `t` matches the innermost
+// open one, returning whether it does
+func (p *Parser) closes(t *Node) bool {
+	open := p.innermostParen()
+	if open == nil || open.val.Repr != matching[t.val.Repr] {
+		p.errorf(t, "unmatched '%v'", t.val.Repr)
+		return false
+	}
+	return true
+}
+
+// matching is the opening paren, bracket, or brace for each closing one
+var matching = map[string]string{")": "(", "]": "[", "}": "{"}
+
+// innermostParen returns the innermost open paren, bracket, or brace on the
+// current line
+func (p *Parser) innermostParen() *Node {
+	for i := len(p.operators) - 1; i >= 0; i-- {
+		if isOpen(p.operators[i]) {
+			return p.operators[i]
+		}
+	}
+	return nil
+}
+
+// errorf records a ParseError at the position of `n`
+func (p *Parser) errorf(n *Node, format string, a ...interface{}) {
+	p.errors = append(p.errors, &ParseError{Pos: n.Pos(), Err: fmt.Errorf(format, a...)})
+}
+
+// lineOK returns whether there haven't been errors on the current line
+func (p *Parser) lineOK() bool {
+	return len(p.errors) == p.lineErrors
 }
 
 func (p *Parser) handleToken(t *Node, stack *Stack) {
@@ -55,6 +344,8 @@ func (p *Parser) handleToken(t *Node, stack *Stack) {
 	stack.Push(t)
 }
 
+// emptyStacks finishes off the current line, adding it to `Lines` if it was
+// parsed without errors
 func (p *Parser) emptyStacks() {
 	for p.operators.Peek() != nil {
 		p.levelStack(&p.operators)
@@ -65,49 +356,315 @@ func (p *Parser) emptyStacks() {
 	for p.keywords.Peek() != nil {
 		p.levelStack(&p.keywords)
 	}
+	if len(p.operands) > 1 && p.lineOK() {
+		p.errorf(p.operands[1], "expected an operator before '%v'", p.operands[1].val.Repr)
+	}
+	if line := p.operands.Peek(); line != nil && p.lineOK() {
+		p.checkLine(line)
+	}
+	if !p.lineOK() {
+		p.operands = nil
+		// still open the block, so that its `end` has something to close. A
+		// line starting with `if` opens a block unless it has a builtin for
+		// its body.
+		f := p.first
+		if f != nil && (isKeyword(f, "while") || isKeyword(f, "for") || isKeyword(f, "func") ||
+			isKeyword(f, "if") && !p.builtin) {
+			if p.broken == nil {
+				p.broken = make(map[*Node]bool)
+			}
+			p.broken[f] = true
+			p.blocks.Push(f)
+		}
+	}
 	for p.operands.Peek() != nil {
-		p.Lines = append(p.Lines, p.operands.Pop())
+		p.addLine(p.operands.Pop())
+	}
+	p.first = nil
+	p.builtin = false
+	p.bare = nil
+	p.lineErrors = len(p.errors)
+}
+
+// checkLine makes sure the parts of a line that the operator precedence
+// doesn't take care of fit together
+func (p *Parser) checkLine(line *Node) {
+	switch {
+	case line.val.Class == Label && !p.blocks.IsEmpty():
+		p.errorf(line, "label '%v' can't be inside a block", line.val.Repr)
+	case line.val.Class == Label && opensBlock(line.right):
+		p.errorf(line.right, "'%v' has to be at the start of a line", line.right.val.Repr)
+	case isKeyword(line, "if") && line.right == nil && !isExpr(line.left):
+		p.errorf(line, "expected 'if <condition>'")
+	case isKeyword(line, "while") && !isExpr(line.right):
+		p.errorf(line, "expected 'while <condition>'")
+	case isKeyword(line, "for"):
+		header := line.right
+		if header.val.Class != Assignment || !isKeyword(header.right, "to") ||
+			!isExpr(header.right.left) || !isExpr(header.right.right) {
+			p.errorf(line, "expected 'for <variable> = <start> to <end>'")
+			return
+		}
+		p.checkNested(header.right.left)
+		p.checkNested(header.right.right)
+	case isKeyword(line, "func"):
+		p.checkFunc(line)
+	default:
+		p.checkNested(line)
+	}
+}
+
+// checkFunc makes sure the function definition `line` looks like
+// `func name(a, b)`
+func (p *Parser) checkFunc(line *Node) {
+	if !p.blocks.IsEmpty() {
+		p.errorf(line, "functions can't be defined inside a block")
+		return
+	}
+	header := line.right
+	if header.val.Class != Call {
+		p.errorf(line, "expected 'func <name>(<parameters>)'")
+		return
+	}
+	params := make(map[string]bool)
+	for _, param := range header.args {
+		switch {
+		case param.val.Class != Var:
+			p.errorf(param, "parameters have to be names, not '%v'", param.val.Repr)
+		case params[param.val.Repr]:
+			p.errorf(param, "duplicate parameter '%v'", param.val.Repr)
+		}
+		params[param.val.Repr] = true
+	}
+}
+
+// checkNested makes sure the keywords in the tree `n` are somewhere they can be
+// used
+func (p *Parser) checkNested(n *Node) {
+	if n == nil {
+		return
+	}
+	switch {
+	case isKeyword(n, "to"):
+		p.errorf(n, "'to' can only be used in a 'for'")
+	case isKeyword(n, "return") && !p.inFunc():
+		p.errorf(n, "'return' can only be used in a function")
+	case n.val.Class == Builtin && n.val.Repr == "goto" && p.inFunc():
+		p.errorf(n, "'goto' can't be used in a function")
+	}
+	p.checkNested(n.left)
+	p.checkNested(n.right)
+	for _, arg := range n.args {
+		p.checkNested(arg)
+	}
+}
+
+// inFunc returns whether the current line is inside a function definition
+func (p *Parser) inFunc() bool {
+	// functions are only defined outside of blocks, so they're at the bottom
+	return !p.blocks.IsEmpty() && isKeyword(p.blocks[0], "func")
+}
+
+// addLine adds a finished line to the innermost open block, or to `Lines` if
+// there isn't one. Lines that start a block are held until their `end`.
+func (p *Parser) addLine(line *Node) {
+	switch {
+	case opensBlock(line):
+		p.blocks.Push(line)
+		return
+	case isKeyword(line, "else"):
+		b := p.blocks.Peek()
+		switch {
+		case b == nil || !isKeyword(b, "if"):
+			p.errorf(line, "'else' without an 'if'")
+		case b.alt != nil:
+			p.errorf(line, "'if' already has an 'else'")
+		default:
+			b.alt = []*Node{}
+		}
+		return
+	case isKeyword(line, "end"):
+		if p.blocks.IsEmpty() {
+			p.errorf(line, "'end' without a block to close")
+			return
+		}
+		line = p.blocks.Pop()
+		if p.broken[line] {
+			delete(p.broken, line)
+			return
+		}
 	}
+	if b := p.blocks.Peek(); b != nil {
+		if b.alt != nil {
+			b.alt = append(b.alt, line)
+		} else {
+			b.body = append(b.body, line)
+		}
+		return
+	}
+	if line.val.Class == Label {
+		if p.labels == nil {
+			p.labels = make(map[string]bool)
+		}
+		p.labels[line.val.Repr] = true
+	}
+	p.Lines = append(p.Lines, line)
 }
 
 // levelStack takes the thing from the operators stack, and gives it its args
 func (p *Parser) levelStack(operators *Stack) {
 	op := operators.Pop()
-	if op.val.Repr == "(" {
+	if isOpen(op) {
+		p.errorf(op, "unclosed '%v'", op.val.Repr)
+		return
+	}
+	if op == p.bare {
+		p.operands.Push(op)
 		return
 	}
 	if p.operands.Peek() != nil {
 		op.right = p.operands.Pop()
 	}
-	if op.val.Class != Builtin { // builtins only have a right side
+	// unary operators are the only operators without a left side
+	if !prefix(op) && op.val.Class != Unary {
 		if p.operands.Peek() != nil {
 			op.left = p.operands.Pop()
 		}
 	}
+	if isKeyword(op, "if") && op.left == nil {
+		// an `if` with just a condition starts a block
+		op.left, op.right = op.right, nil
+	}
+	if p.lineOK() {
+		switch {
+		case op.val.Class == Assignment && op.left.val.Class != Var && op.left.val.Class != Index:
+			p.errorf(op, "can only assign to a variable, not '%v'", op.left.val.Repr)
+		}
+	}
 	p.operands.Push(op)
 }
 
 var precedences = map[string]int{
-	"goto":  -1,
-	"print": -1,
-	">":     0,
-	"<":     0,
-	"==":    0,
-	"!=":    0,
-	"|":     1,
-	"&":     1,
-	"+":     2,
-	"-":     2,
-	"*":     3,
-	"/":     3,
-	"%":     3,
-	"=":     4,
-	"if":    5,
+	"goto":   -1,
+	"print":  -1,
+	"return": -1,
+	">":      0,
+	"<":      0,
+	">=":     0,
+	"<=":     0,
+	"==":     0,
+	"!=":     0,
+	"|":      1,
+	"&":      1,
+	"+":      2,
+	"-":      2,
+	"*":      3,
+	"/":      3,
+	"%":      3,
+	"!":      6,
+	"not":    6,
+	"to":     -1,
+	":":      -1,
+	"=":      4,
+	"if":     5,
+	"while":  5,
+	"for":    5,
+	"func":   5,
+	"else":   5,
+	"end":    5,
+}
+
+// prefix returns whether the operator `op` only has a right side
+func prefix(op *Node) bool {
+	switch op.val.Class {
+	case Builtin, Label:
+		return true
+	case Keyword:
+		return op.val.Repr != "if" && op.val.Repr != "to"
+	}
+	return false
+}
+
+// opensBlock returns whether the line `n` starts a block that's closed with
+// `end`
+func opensBlock(n *Node) bool {
+	if n == nil {
+		return false
+	}
+	return isKeyword(n, "while") || isKeyword(n, "for") || isKeyword(n, "func") ||
+		isKeyword(n, "if") && n.right == nil
+}
+
+// alone returns whether `n` is a keyword that has to be on a line by itself
+func alone(n *Node) bool {
+	return isKeyword(n, "else") || isKeyword(n, "end")
+}
+
+// isOpen returns whether `n` is an opening paren, bracket, or brace
+func isOpen(n *Node) bool {
+	switch n.val.Class {
+	case Paren, Bracket, Brace:
+		return n.val.Repr == "(" || n.val.Repr == "[" || n.val.Repr == "{"
+	}
+	return false
+}
+
+// endsOperand returns whether the token `n` can be the end of an operand,
+// which makes a `[` after it an index rather than a list
+func endsOperand(n *Node) bool {
+	switch n.val.Class {
+	case Str, Num, Boolean, Var:
+		return true
+	case Paren, Bracket, Brace:
+		return !isOpen(n)
+	}
+	return false
+}
+
+// isKeyword returns whether `n` is the keyword `k`
+func isKeyword(n *Node, k string) bool {
+	return n.val.Class == Keyword && n.val.Repr == k
+}
+
+// isExpr returns whether `n` is an expression, rather than a statement
+func isExpr(n *Node) bool {
+	if n == nil {
+		return false
+	}
+	switch n.val.Class {
+	case Str, Num, Boolean, Var:
+		return true
+	case Operator, Boolop:
+		return isExpr(n.left) && isExpr(n.right)
+	case Index, Colon:
+		return isExpr(n.left) && isExpr(n.right)
+	case Unary:
+		return isExpr(n.right)
+	case Call, ListLit, MapLit:
+		for _, arg := range n.args {
+			if !isExpr(arg) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// precedence returns the precedence of the operator `n`
+func precedence(n *Node) int {
+	switch n.val.Class {
+	case Label: // labels hold the whole rest of the line
+		return -2
+	case Unary: // a '-' in front of an operand binds as tightly as '!'
+		return precedences["!"]
+	}
+	return precedences[n.val.Repr]
 }
 
 func greaterPrecedence(a, b *Node) bool {
-	if a.val.Repr == "" {
+	if a.val.Repr == "" || isOpen(a) {
 		return false
 	}
-	return precedences[a.val.Repr] > precedences[b.val.Repr]
+	return precedence(a) > precedence(b)
 }
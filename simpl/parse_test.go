@@ -1,7 +1,6 @@
 package simpl
 
 import (
-	"fmt"
 	"reflect"
 	"strings"
 	"testing"
@@ -213,6 +212,167 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: "loop:",
+			expected: []*Node{
+				&Node{val: Token{Class: Label, Repr: "loop"}},
+			},
+		},
+		{
+			input: "loop: if i < 100 goto loop",
+			expected: []*Node{
+				&Node{
+					right: &Node{
+						left: &Node{
+							left:  &Node{val: Token{Class: Var, Repr: "i"}},
+							right: &Node{val: Token{Class: Num, Repr: "100"}},
+							val:   Token{Class: Boolop, Repr: "<"},
+						},
+						right: &Node{
+							right: &Node{val: Token{Class: Var, Repr: "loop"}},
+							val:   Token{Class: Builtin, Repr: "goto"},
+						},
+						val: Token{Class: Keyword, Repr: "if"},
+					},
+					val: Token{Class: Label, Repr: "loop"},
+				},
+			},
+		},
+		{
+			input: "while i < 3\ni = i + 1\nend",
+			expected: []*Node{
+				&Node{
+					right: &Node{
+						left:  &Node{val: Token{Class: Var, Repr: "i"}},
+						right: &Node{val: Token{Class: Num, Repr: "3"}},
+						val:   Token{Class: Boolop, Repr: "<"},
+					},
+					val: Token{Class: Keyword, Repr: "while"},
+					body: []*Node{
+						&Node{
+							left: &Node{val: Token{Class: Var, Repr: "i"}},
+							right: &Node{
+								left:  &Node{val: Token{Class: Var, Repr: "i"}},
+								right: &Node{val: Token{Class: Num, Repr: "1"}},
+								val:   Token{Class: Operator, Repr: "+"},
+							},
+							val: Token{Class: Assignment, Repr: "="},
+						},
+					},
+				},
+			},
+		},
+		{
+			input: "for i = 1 to n + 1\nprint i\nend",
+			expected: []*Node{
+				&Node{
+					right: &Node{
+						left: &Node{val: Token{Class: Var, Repr: "i"}},
+						right: &Node{
+							left: &Node{val: Token{Class: Num, Repr: "1"}},
+							right: &Node{
+								left:  &Node{val: Token{Class: Var, Repr: "n"}},
+								right: &Node{val: Token{Class: Num, Repr: "1"}},
+								val:   Token{Class: Operator, Repr: "+"},
+							},
+							val: Token{Class: Keyword, Repr: "to"},
+						},
+						val: Token{Class: Assignment, Repr: "="},
+					},
+					val: Token{Class: Keyword, Repr: "for"},
+					body: []*Node{
+						&Node{
+							right: &Node{val: Token{Class: Var, Repr: "i"}},
+							val:   Token{Class: Builtin, Repr: "print"},
+						},
+					},
+				},
+			},
+		},
+		{
+			input: "if i\nprint 1\nelse\nprint 2\nend",
+			expected: []*Node{
+				&Node{
+					left: &Node{val: Token{Class: Var, Repr: "i"}},
+					val:  Token{Class: Keyword, Repr: "if"},
+					body: []*Node{
+						&Node{
+							right: &Node{val: Token{Class: Num, Repr: "1"}},
+							val:   Token{Class: Builtin, Repr: "print"},
+						},
+					},
+					alt: []*Node{
+						&Node{
+							right: &Node{val: Token{Class: Num, Repr: "2"}},
+							val:   Token{Class: Builtin, Repr: "print"},
+						},
+					},
+				},
+			},
+		},
+		{
+			input: "x = f(1, g(), y + 2)",
+			expected: []*Node{
+				&Node{
+					left: &Node{val: Token{Class: Var, Repr: "x"}},
+					right: &Node{
+						val: Token{Class: Call, Repr: "f"},
+						args: []*Node{
+							&Node{val: Token{Class: Num, Repr: "1"}},
+							&Node{val: Token{Class: Call, Repr: "g"}},
+							&Node{
+								left:  &Node{val: Token{Class: Var, Repr: "y"}},
+								right: &Node{val: Token{Class: Num, Repr: "2"}},
+								val:   Token{Class: Operator, Repr: "+"},
+							},
+						},
+					},
+					val: Token{Class: Assignment, Repr: "="},
+				},
+			},
+		},
+		{
+			input: "func add(a, b)\nreturn a + b\nend",
+			expected: []*Node{
+				&Node{
+					right: &Node{
+						val: Token{Class: Call, Repr: "add"},
+						args: []*Node{
+							&Node{val: Token{Class: Var, Repr: "a"}},
+							&Node{val: Token{Class: Var, Repr: "b"}},
+						},
+					},
+					val: Token{Class: Keyword, Repr: "func"},
+					body: []*Node{
+						&Node{
+							right: &Node{
+								left:  &Node{val: Token{Class: Var, Repr: "a"}},
+								right: &Node{val: Token{Class: Var, Repr: "b"}},
+								val:   Token{Class: Operator, Repr: "+"},
+							},
+							val: Token{Class: Keyword, Repr: "return"},
+						},
+					},
+				},
+			},
+		},
+		{
+			input: "func f()\nif x return\nreturn\nend",
+			expected: []*Node{
+				&Node{
+					right: &Node{val: Token{Class: Call, Repr: "f"}},
+					val:   Token{Class: Keyword, Repr: "func"},
+					body: []*Node{
+						&Node{
+							left:  &Node{val: Token{Class: Var, Repr: "x"}},
+							right: &Node{val: Token{Class: Keyword, Repr: "return"}},
+							val:   Token{Class: Keyword, Repr: "if"},
+						},
+						&Node{val: Token{Class: Keyword, Repr: "return"}},
+					},
+				},
+			},
+		},
 		{
 			input: "if i < 100 goto 2",
 			expected: []*Node{
@@ -308,6 +468,54 @@ if i < 100 goto 2
 				},
 			},
 		},
+		{
+			input: "x = -x * !y",
+			expected: []*Node{
+				&Node{
+					left: &Node{val: Token{Class: Var, Repr: "x"}},
+					right: &Node{
+						left: &Node{
+							right: &Node{val: Token{Class: Var, Repr: "x"}},
+							val:   Token{Class: Unary, Repr: "-"},
+						},
+						right: &Node{
+							right: &Node{val: Token{Class: Var, Repr: "y"}},
+							val:   Token{Class: Unary, Repr: "!"},
+						},
+						val: Token{Class: Operator, Repr: "*"},
+					},
+					val: Token{Class: Assignment, Repr: "="},
+				},
+			},
+		},
+		{
+			input: "xs[i + 1] = [1, ys[0]]",
+			expected: []*Node{
+				&Node{
+					left: &Node{
+						left: &Node{val: Token{Class: Var, Repr: "xs"}},
+						right: &Node{
+							left:  &Node{val: Token{Class: Var, Repr: "i"}},
+							right: &Node{val: Token{Class: Num, Repr: "1"}},
+							val:   Token{Class: Operator, Repr: "+"},
+						},
+						val: Token{Class: Index, Repr: "[]"},
+					},
+					right: &Node{
+						args: []*Node{
+							&Node{val: Token{Class: Num, Repr: "1"}},
+							&Node{
+								left:  &Node{val: Token{Class: Var, Repr: "ys"}},
+								right: &Node{val: Token{Class: Num, Repr: "0"}},
+								val:   Token{Class: Index, Repr: "[]"},
+							},
+						},
+						val: Token{Class: ListLit, Repr: "[]"},
+					},
+					val: Token{Class: Assignment, Repr: "="},
+				},
+			},
+		},
 	}
 	lexer := Lexer{}
 	for _, test := range cases {
@@ -317,16 +525,312 @@ if i < 100 goto 2
 			tkns, _ := lexer.Lex()
 			p.Tokens = tkns
 			p.Parse()
+			for _, line := range p.Lines {
+				clearPos(line)
+			}
 			if !reflect.DeepEqual(p.Lines, test.expected) {
-				t.Errorf("bad parse")
-				for i := range test.expected {
-					fmt.Println("expected")
-					test.expected[i].PrintTree()
-					fmt.Println("got")
-					if i < len(p.Lines) {
-						p.Lines[i].PrintTree()
-					}
+				var expected, got strings.Builder
+				DumpText(&expected, test.expected)
+				DumpText(&got, p.Lines)
+				t.Errorf("bad parse, expected:\n%vgot:\n%v", &expected, &got)
+			}
+		})
+	}
+}
+
+func TestParsePositions(t *testing.T) {
+	lexer := Lexer{In: strings.NewReader("i = 0\nif i < 3 print i"), File: "test"}
+	p := Parser{}
+	p.Tokens, _ = lexer.Lex()
+	p.Parse()
+	if len(p.Lines) != 2 {
+		t.Fatalf("expected 2 lines, got %v", len(p.Lines))
+	}
+	cases := []struct {
+		node     *Node
+		expected string
+	}{
+		{node: p.Lines[0], expected: "test:1:3"},
+		{node: p.Lines[0].left, expected: "test:1:1"},
+		{node: p.Lines[1], expected: "test:2:1"},
+		{node: p.Lines[1].left, expected: "test:2:6"},
+		{node: p.Lines[1].right, expected: "test:2:10"},
+		{node: p.Lines[1].right.right, expected: "test:2:16"},
+	}
+	for _, test := range cases {
+		if got := test.node.Pos().String(); got != test.expected {
+			t.Errorf("%v: expected position %v, got %v", test.node.val.Repr, test.expected, got)
+		}
+	}
+}
+
+// clearPos zeroes the positions of every token in the tree rooted at `n`
+func clearPos(n *Node) {
+	if n == nil {
+		return
+	}
+	n.val.Pos = Pos{}
+	clearPos(n.left)
+	clearPos(n.right)
+	for _, line := range n.body {
+		clearPos(line)
+	}
+	for _, line := range n.alt {
+		clearPos(line)
+	}
+	for _, arg := range n.args {
+		clearPos(arg)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected []string
+		lines    int
+	}{
+		{
+			input:    "x = ( 1 + 2 ) )",
+			expected: []string{"test:1:15: unmatched ')'"},
+		},
+		{
+			input:    "x = ( 1 + 2",
+			expected: []string{"test:1:5: unclosed '('"},
+		},
+		{
+			input:    "x = 1 +",
+			expected: []string{"test:1:7: '+' is missing an operand"},
+		},
+		{
+			input:    "* 2",
+			expected: []string{"test:1:1: '*' is missing an operand"},
+		},
+		{
+			input:    "2 ( + 1 )",
+			expected: []string{"test:1:3: expected an operator before '('", "test:1:5: '+' is missing an operand"},
+		},
+		{
+			input:    "if i < 3",
+			expected: []string{"test:1:1: 'if' isn't closed with 'end'"},
+		},
+		{
+			input:    "if",
+			expected: []string{"test:1:1: 'if' is missing an operand", "test:1:1: 'if' isn't closed with 'end'"},
+		},
+		{
+			input:    "print",
+			expected: []string{"test:1:1: 'print' is missing an operand"},
+		},
+		{
+			input:    "3 = 4",
+			expected: []string{"test:1:3: can only assign to a variable, not '3'"},
+		},
+		{
+			input:    "x = 1 2",
+			expected: []string{"test:1:7: expected an operator before '2'"},
+		},
+		{
+			input:    "( )",
+			expected: []string{"test:1:3: empty parentheses"},
+		},
+		{
+			input:    "x = 1 loop:",
+			expected: []string{"test:1:7: label 'loop' has to be at the start of a line"},
+		},
+		{
+			input:    "loop:\nx = 1\nloop: print x",
+			expected: []string{"test:3:1: duplicate label 'loop'"},
+			lines:    2,
+		},
+		{
+			input:    "while i < 3\nprint i",
+			expected: []string{"test:1:1: 'while' isn't closed with 'end'"},
+		},
+		{
+			input:    "print 1\nend",
+			expected: []string{"test:2:1: 'end' without a block to close"},
+			lines:    1,
+		},
+		{
+			input:    "while i < 3\nend end",
+			expected: []string{"test:2:5: expected a new line after 'end'", "test:1:1: 'while' isn't closed with 'end'"},
+		},
+		{
+			input:    "x = while i",
+			expected: []string{"test:1:5: 'while' has to be at the start of a line"},
+		},
+		{
+			input:    "if 0 goto nowhere",
+			expected: []string{"test:1:11: unknown label 'nowhere'"},
+			lines:    1,
+		},
+		{
+			input:    "while 1\nif 1 goto later\nend\nnow: goto now",
+			expected: []string{"test:2:11: unknown label 'later'"},
+			lines:    2,
+		},
+		{
+			input:    "1 > if print 2",
+			expected: []string{"test:1:5: 'if' has to be at the start of a line"},
+		},
+		{
+			input:    "print 1 if 2",
+			expected: []string{"test:1:9: 'if' has to be at the start of a line"},
+		},
+		{
+			input:    "1 + if 1 print 2",
+			expected: []string{"test:1:5: 'if' has to be at the start of a line"},
+		},
+		{
+			input:    "f( if 1 print 2 )",
+			expected: []string{"test:1:4: 'if' has to be at the start of a line"},
+		},
+		{
+			input:    "- if print 0",
+			expected: []string{"test:1:3: 'if' has to be at the start of a line"},
+		},
+		{
+			input:    "while i = 3\nend",
+			expected: []string{"test:1:1: expected 'while <condition>'"},
+		},
+		{
+			input:    "for i to 3\nend",
+			expected: []string{"test:1:1: expected 'for <variable> = <start> to <end>'"},
+		},
+		{
+			input:    "x = 1 to 3",
+			expected: []string{"test:1:7: 'to' can only be used in a 'for'"},
+		},
+		{
+			input:    "while 1\nloop:\nend",
+			expected: []string{"test:2:1: label 'loop' can't be inside a block"},
+			lines:    1,
+		},
+		{
+			input:    "else\nwhile 1\nelse\nend",
+			expected: []string{"test:1:1: 'else' without an 'if'", "test:3:1: 'else' without an 'if'"},
+			lines:    1,
+		},
+		{
+			input:    "if 1\nelse\nelse\nend",
+			expected: []string{"test:3:1: 'if' already has an 'else'"},
+			lines:    1,
+		},
+		{
+			input:    "if 1\nelse print 1\nend",
+			expected: []string{"test:2:6: expected a new line after 'else'"},
+			lines:    1,
+		},
+		{
+			input:    "if x = 1\nprint 1\nend",
+			expected: []string{"test:1:1: expected 'if <condition>'"},
+		},
+		{
+			input:    "if * 2\nwhile 1\nend\nend",
+			expected: []string{"test:1:4: '*' is missing an operand"},
+		},
+		{
+			input:    "x = f(1, )",
+			expected: []string{"test:1:8: ',' is missing an operand"},
+		},
+		{
+			input:    "x = f(, 1)",
+			expected: []string{"test:1:7: expected an argument before ','"},
+		},
+		{
+			input:    "x = ( 1 , 2 )",
+			expected: []string{"test:1:9: ',' can only separate the arguments of a call, or the elements of a list or map"},
+		},
+		{
+			input:    "x = xs[1, 2]",
+			expected: []string{"test:1:9: ',' can only separate the arguments of a call, or the elements of a list or map"},
+		},
+		{
+			input:    "x = xs[]",
+			expected: []string{"test:1:8: expected an index between '[' and ']'"},
+		},
+		{
+			input:    "x = ( 1 ]",
+			expected: []string{"test:1:9: unmatched ']'", "test:1:5: unclosed '('"},
+		},
+		{
+			input:    "m = {\"a\" 1}",
+			expected: []string{"test:1:10: expected an operator before '1'"},
+		},
+		{
+			input:    "m = {\"a\": 1, \"b\"}",
+			expected: []string{"test:1:14: expected '<key>: <value>'"},
+		},
+		{
+			input:    "m = {\"a\": 1: 2}",
+			expected: []string{"test:1:12: expected '<key>: <value>'"},
+		},
+		{
+			input:    "m = {: 1}",
+			expected: []string{"test:1:6: expected a key before ':'"},
+		},
+		{
+			input:    "x = [1 : 2]",
+			expected: []string{"test:1:8: ':' can only separate the keys and values of a map"},
+		},
+		{
+			input:    "x = 1 not 2",
+			expected: []string{"test:1:7: expected an operator before 'not'"},
+		},
+		{
+			input:    "x = 1 - -",
+			expected: []string{"test:1:9: '-' is missing an operand"},
+		},
+		{
+			input:    "x = [1, 2",
+			expected: []string{"test:1:5: unclosed '['"},
+		},
+		{
+			input:    "func f(a, 1, a)\nend",
+			expected: []string{"test:1:11: parameters have to be names, not '1'", "test:1:14: duplicate parameter 'a'"},
+		},
+		{
+			input:    "func f\nend",
+			expected: []string{"test:1:1: expected 'func <name>(<parameters>)'"},
+		},
+		{
+			input:    "while 1\nfunc f()\nend\nend",
+			expected: []string{"test:2:1: functions can't be defined inside a block"},
+			lines:    1,
+		},
+		{
+			input:    "return 1",
+			expected: []string{"test:1:1: 'return' can only be used in a function"},
+		},
+		{
+			input:    "func f()\nif 1 goto 1\nend",
+			expected: []string{"test:2:6: 'goto' can't be used in a function"},
+			lines:    1,
+		},
+		{
+			input:    "x = 1\ny = * 2\nz = 3",
+			expected: []string{"test:2:5: '*' is missing an operand"},
+			lines:    2,
+		},
+	}
+	for _, test := range cases {
+		t.Run(test.input, func(t *testing.T) {
+			lexer := Lexer{In: strings.NewReader(test.input), File: "test"}
+			p := Parser{}
+			p.Tokens, _ = lexer.Lex()
+			errs := p.Parse()
+			got := []string{}
+			for _, err := range errs {
+				if _, ok := err.(*ParseError); !ok {
+					t.Errorf("expected a *ParseError, got %#v", err)
 				}
+				got = append(got, err.Error())
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected errors %v, got %v", test.expected, got)
+			}
+			if len(p.Lines) != test.lines {
+				t.Errorf("expected %v lines, got %v", test.lines, len(p.Lines))
 			}
 		})
 	}
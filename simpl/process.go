@@ -0,0 +1,36 @@
+package simpl
+
+import "fmt"
+
+// builtinEnv returns the value of an environment variable, or nil if it
+// isn't set
+func builtinEnv(in *Interpreter, args []Value) (Value, error) {
+	name, err := args[0].str()
+	if err != nil {
+		return Value{}, err
+	}
+	if in.env == nil {
+		return Value{}, nil
+	}
+	val, ok := in.env(name)
+	if !ok {
+		return Value{}, nil
+	}
+	return StringValue(val), nil
+}
+
+// builtinExit stops the program with an exit code from 0 to 255, which is 0
+// if it isn't given
+func builtinExit(in *Interpreter, args []Value) (Value, error) {
+	code := 0
+	if len(args) == 1 {
+		var err error
+		if code, err = args[0].integer(); err != nil {
+			return Value{}, err
+		}
+	}
+	if code < 0 || code > 255 {
+		return Value{}, fmt.Errorf("exit code %v isn't from 0 to 255", code)
+	}
+	return Value{}, &ExitError{Code: code}
+}
@@ -0,0 +1,102 @@
+package simpl
+
+import "testing"
+
+func TestProcess(t *testing.T) {
+	env := map[string]string{"USER": "bob", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		val, ok := env[name]
+		return val, ok
+	}
+	cases := []struct {
+		name     string
+		input    string
+		opts     []Option
+		expected string
+		code     int
+		exited   bool
+	}{
+		{
+			name:     "args",
+			input:    `print args + " " + args[1]`,
+			opts:     []Option{Args("a", "b c")},
+			expected: `["a", "b c"] b c`,
+		},
+		{
+			name:     "no args",
+			input:    `print args`,
+			opts:     []Option{Args()},
+			expected: "[]",
+		},
+		{
+			name:     "env",
+			input:    `print env("USER") + " " + env("MISSING") + " [" + env("EMPTY") + "]"`,
+			opts:     []Option{Env(lookup)},
+			expected: "bob nil []",
+		},
+		{
+			name:     "no env",
+			input:    `print env("USER")`,
+			expected: "nil",
+		},
+		{
+			name: "exit",
+			input: `func check(n)
+if n > 2
+exit(n)
+end
+end
+for i = 1 to 5
+print i
+check(i)
+end`,
+			expected: "123",
+			code:     3,
+			exited:   true,
+		},
+		{
+			name: "exit without a code",
+			input: `print "a"
+exit()
+print "b"`,
+			expected: "a",
+			exited:   true,
+		},
+	}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := run(t, test.input, test.opts...)
+			exit, ok := err.(*ExitError)
+			switch {
+			case test.exited && !ok:
+				t.Errorf("expected an *ExitError, got %#v", err)
+			case test.exited && exit.Code != test.code:
+				t.Errorf("expected exit code %v, got %v", test.code, exit.Code)
+			case !test.exited && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			}
+			if out != test.expected {
+				t.Errorf("expected %q got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestProcessErrors(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{input: `exit("a")`, expected: "1:1: 'exit' expected an int, got string 'a'"},
+		{input: `exit(256)`, expected: "1:1: 'exit' exit code 256 isn't from 0 to 255"},
+		{input: `exit(0 - 1)`, expected: "1:1: 'exit' exit code -1 isn't from 0 to 255"},
+		{input: `env(1)`, expected: "1:1: 'env' expected a string, got int '1'"},
+	}
+	for _, test := range cases {
+		t.Run(test.input, func(t *testing.T) {
+			if err := runError(t, test.input); err.Error() != test.expected {
+				t.Errorf("expected %v got %v", test.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package simpl
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// The string builtins count in characters rather than bytes, so
+// `substr("héllo", 1, 2)` is "é" and `len("héllo")` is 5.
+
+// builtinUpper returns a string in upper case
+func builtinUpper(in *Interpreter, args []Value) (Value, error) {
+	s, err := args[0].str()
+	if err != nil {
+		return Value{}, err
+	}
+	return StringValue(strings.ToUpper(s)), nil
+}
+
+// builtinLower returns a string in lower case
+func builtinLower(in *Interpreter, args []Value) (Value, error) {
+	s, err := args[0].str()
+	if err != nil {
+		return Value{}, err
+	}
+	return StringValue(strings.ToLower(s)), nil
+}
+
+// builtinSubstr returns the characters of a string from a start index up to,
+// but not including, an end index. Without an end index it goes to the end
+// of the string.
+func builtinSubstr(in *Interpreter, args []Value) (Value, error) {
+	s, err := args[0].str()
+	if err != nil {
+		return Value{}, err
+	}
+	chars := []rune(s)
+	start, err := args[1].integer()
+	if err != nil {
+		return Value{}, err
+	}
+	end := len(chars)
+	if len(args) == 3 {
+		if end, err = args[2].integer(); err != nil {
+			return Value{}, err
+		}
+	}
+	if start < 0 || end > len(chars) || start > end {
+		return Value{}, fmt.Errorf("%v to %v is out of range for a string of length %v", start, end, len(chars))
+	}
+	return StringValue(string(chars[start:end])), nil
+}
+
+// builtinSplit splits a string into a list of the parts between a separator.
+// An empty separator splits it into characters.
+func builtinSplit(in *Interpreter, args []Value) (Value, error) {
+	s, err := args[0].str()
+	if err != nil {
+		return Value{}, err
+	}
+	sep, err := args[1].str()
+	if err != nil {
+		return Value{}, err
+	}
+	parts := strings.Split(s, sep)
+	elems := make([]Value, len(parts))
+	for i, part := range parts {
+		elems[i] = StringValue(part)
+	}
+	return ListValue(elems), nil
+}
+
+// builtinJoin joins the elements of a list into a string, with a separator
+// between them
+func builtinJoin(in *Interpreter, args []Value) (Value, error) {
+	elems, err := args[0].list()
+	if err != nil {
+		return Value{}, err
+	}
+	sep, err := args[1].str()
+	if err != nil {
+		return Value{}, err
+	}
+	parts := make([]string, len(*elems))
+	for i, e := range *elems {
+		parts[i] = e.String()
+	}
+	return StringValue(strings.Join(parts, sep)), nil
+}
+
+// builtinTrim removes the whitespace from both ends of a string
+func builtinTrim(in *Interpreter, args []Value) (Value, error) {
+	s, err := args[0].str()
+	if err != nil {
+		return Value{}, err
+	}
+	return StringValue(strings.TrimSpace(s)), nil
+}
+
+// builtinReplace replaces every occurrence of one string in another
+func builtinReplace(in *Interpreter, args []Value) (Value, error) {
+	s, err := args[0].str()
+	if err != nil {
+		return Value{}, err
+	}
+	old, err := args[1].str()
+	if err != nil {
+		return Value{}, err
+	}
+	new, err := args[2].str()
+	if err != nil {
+		return Value{}, err
+	}
+	return StringValue(strings.ReplaceAll(s, old, new)), nil
+}
+
+// builtinContains returns whether one string is in another
+func builtinContains(in *Interpreter, args []Value) (Value, error) {
+	s, err := args[0].str()
+	if err != nil {
+		return Value{}, err
+	}
+	sub, err := args[1].str()
+	if err != nil {
+		return Value{}, err
+	}
+	return BoolValue(strings.Contains(s, sub)), nil
+}
+
+// builtinIndex returns the index of the first occurrence of one string in
+// another, or -1 if it isn't in it
+func builtinIndex(in *Interpreter, args []Value) (Value, error) {
+	s, err := args[0].str()
+	if err != nil {
+		return Value{}, err
+	}
+	sub, err := args[1].str()
+	if err != nil {
+		return Value{}, err
+	}
+	i := strings.Index(s, sub)
+	if i < 0 {
+		return IntValue(-1), nil
+	}
+	return IntValue(int64(utf8.RuneCountInString(s[:i]))), nil
+}
+
+// builtinFormat replaces each `{}` in a string with the next of the values
+// after it
+func builtinFormat(in *Interpreter, args []Value) (Value, error) {
+	s, err := args[0].str()
+	if err != nil {
+		return Value{}, err
+	}
+	parts := strings.Split(s, "{}")
+	vals := args[1:]
+	if len(parts)-1 != len(vals) {
+		return Value{}, fmt.Errorf("has %v places for values, got %v values", len(parts)-1, len(vals))
+	}
+	b := strings.Builder{}
+	for i, part := range parts {
+		b.WriteString(part)
+		if i < len(vals) {
+			b.WriteString(vals[i].String())
+		}
+	}
+	return StringValue(b.String()), nil
+}
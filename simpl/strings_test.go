@@ -0,0 +1,56 @@
+package simpl
+
+import "testing"
+
+func TestStringBuiltins(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{input: `print len("héllo")`, expected: "5"},
+		{input: `print upper("héllo") + lower(" ÀB")`, expected: "HÉLLO àb"},
+		{input: `print substr("héllo", 1, 2)`, expected: "é"},
+		{input: `print substr("héllo", 2)`, expected: "llo"},
+		{input: `print substr("abc", 3, 3) == ""`, expected: "true"},
+		{input: `print split("a,b,,c", ",")`, expected: `["a", "b", "", "c"]`},
+		{input: `print split("hé", "")`, expected: `["h", "é"]`},
+		{input: `print join([1, "b", true], "-")`, expected: "1-b-true"},
+		{input: `print join([], "-") == ""`, expected: "true"},
+		{input: `print "[" + trim("\t a b \n") + "]"`, expected: "[a b]"},
+		{input: `print replace("a.b.c", ".", "::")`, expected: "a::b::c"},
+		{input: `print contains("héllo", "él") + " " + contains("hello", "x")`, expected: "true false"},
+		{input: `print index("héllo", "l") + " " + index("hello", "x")`, expected: "2 -1"},
+		{input: `print format("{} + {} = {}", 1, 2.5, [3.5])`, expected: "1 + 2.5 = [3.5]"},
+		{input: `print format("no values")`, expected: "no values"},
+	}
+	for _, test := range cases {
+		t.Run(test.input, func(t *testing.T) {
+			if out := runProgram(t, test.input); out != test.expected {
+				t.Errorf("expected %q got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestStringBuiltinErrors(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{input: `upper(1)`, expected: "1:1: 'upper' expected a string, got int '1'"},
+		{input: `substr("abc", 2, 1)`, expected: "1:1: 'substr' 2 to 1 is out of range for a string of length 3"},
+		{input: `substr("abc", 0, 4)`, expected: "1:1: 'substr' 0 to 4 is out of range for a string of length 3"},
+		{input: `substr("abc", 1.5)`, expected: "1:1: 'substr' expected an int, got float '1.5'"},
+		{input: `substr("abc")`, expected: "1:1: 'substr' takes 2 to 3 arguments, got 1"},
+		{input: `format()`, expected: "1:1: 'format' takes at least 1 arguments, got 0"},
+		{input: `format("{} {}", 1)`, expected: "1:1: 'format' has 2 places for values, got 1 values"},
+		{input: `join("abc", "")`, expected: "1:1: 'join' expected a list, got string 'abc'"},
+	}
+	for _, test := range cases {
+		t.Run(test.input, func(t *testing.T) {
+			if err := runError(t, test.input); err.Error() != test.expected {
+				t.Errorf("expected %v got %v", test.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,511 @@
+package simpl
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind is the kind of a Value
+type Kind uint8
+
+// Various kinds of values
+const (
+	Nil Kind = iota
+	Int
+	Float
+	Bool
+	String
+	List
+	Map
+)
+
+func (k Kind) String() string {
+	return [...]string{"nil", "int", "float", "bool", "string", "list", "map"}[k]
+}
+
+// Value is a value in a simple program. The zero Value is nil.
+//
+// Lists and maps are shared rather than copied, so changing one through one
+// variable changes it for every variable holding it.
+type Value struct {
+	Kind Kind
+	i    int64 // Int, and Bool as 1 or 0
+	f    float64
+	s    string
+	l    *[]Value
+	m    *map[string]Value // a pointer so that Values can be compared
+}
+
+// IntValue makes an Int Value
+func IntValue(i int64) Value {
+	return Value{Kind: Int, i: i}
+}
+
+// FloatValue makes a Float Value
+func FloatValue(f float64) Value {
+	return Value{Kind: Float, f: f}
+}
+
+// BoolValue makes a Bool Value
+func BoolValue(b bool) Value {
+	if b {
+		return Value{Kind: Bool, i: 1}
+	}
+	return Value{Kind: Bool}
+}
+
+// StringValue makes a String Value
+func StringValue(s string) Value {
+	return Value{Kind: String, s: s}
+}
+
+// ListValue makes a List Value holding `elems`
+func ListValue(elems []Value) Value {
+	return Value{Kind: List, l: &elems}
+}
+
+// MapValue makes a Map Value holding `entries`
+func MapValue(entries map[string]Value) Value {
+	return Value{Kind: Map, m: &entries}
+}
+
+// Elems returns the elements of a List, which are shared with it
+func (v Value) Elems() []Value {
+	if v.Kind != List {
+		return nil
+	}
+	return *v.l
+}
+
+// Entries returns the entries of a Map, which are shared with it
+func (v Value) Entries() map[string]Value {
+	if v.Kind != Map {
+		return nil
+	}
+	return *v.m
+}
+
+// Int returns a number as an int64, truncating Floats
+func (v Value) Int() int64 {
+	if v.Kind == Float {
+		return int64(v.f)
+	}
+	return v.i
+}
+
+// Float returns a number as a float64
+func (v Value) Float() float64 {
+	if v.Kind == Float {
+		return v.f
+	}
+	return float64(v.i)
+}
+
+// Bool returns whether the value counts as true, which everything but nil,
+// false, zero, the empty string, the empty list, and the empty map does
+func (v Value) Bool() bool {
+	switch v.Kind {
+	case Float:
+		return v.f != 0
+	case String:
+		return v.s != ""
+	case List:
+		return len(*v.l) > 0
+	case Map:
+		return len(*v.m) > 0
+	}
+	return v.i != 0
+}
+
+// String returns the value the way print shows it. A list or map inside
+// itself is shown as `[...]` or `{...}`.
+func (v Value) String() string {
+	return v.text(nil)
+}
+
+// text returns the value the way print shows it, inside the lists and maps in
+// `outer`
+func (v Value) text(outer map[interface{}]bool) string {
+	switch v.Kind {
+	case Int:
+		return strconv.FormatInt(v.i, 10)
+	case Float:
+		return strconv.FormatFloat(v.f, 'g', -1, 64)
+	case Bool:
+		return strconv.FormatBool(v.i != 0)
+	case String:
+		return v.s
+	case List:
+		if outer[v.l] {
+			return "[...]"
+		}
+		outer = inside(outer, v.l)
+		defer delete(outer, v.l)
+		elems := make([]string, len(*v.l))
+		for i, e := range *v.l {
+			elems[i] = e.reprIn(outer)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case Map:
+		if outer[v.m] {
+			return "{...}"
+		}
+		outer = inside(outer, v.m)
+		defer delete(outer, v.m)
+		keys := v.keys()
+		entries := make([]string, len(keys))
+		for i, k := range keys {
+			entries[i] = strconv.Quote(k) + ": " + (*v.m)[k].reprIn(outer)
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	}
+	return "nil"
+}
+
+// inside adds the list or map `ref` to `outer`, making it if it's nil
+func inside(outer map[interface{}]bool, ref interface{}) map[interface{}]bool {
+	if outer == nil {
+		outer = make(map[interface{}]bool)
+	}
+	outer[ref] = true
+	return outer
+}
+
+// repr returns the value the way it's written in a program, which is how
+// print shows it inside a list or map
+func (v Value) repr() string {
+	return v.reprIn(nil)
+}
+
+// reprIn is repr inside the lists and maps in `outer`
+func (v Value) reprIn(outer map[interface{}]bool) string {
+	if v.Kind == String {
+		return strconv.Quote(v.s)
+	}
+	return v.text(outer)
+}
+
+// str returns the contents of a String
+func (v Value) str() (string, error) {
+	if v.Kind != String {
+		return "", fmt.Errorf("expected a string, got %v '%v'", v.Kind, v)
+	}
+	return v.s, nil
+}
+
+// integer returns an Int as an int
+func (v Value) integer() (int, error) {
+	if v.Kind != Int {
+		return 0, fmt.Errorf("expected an int, got %v '%v'", v.Kind, v)
+	}
+	return int(v.i), nil
+}
+
+// list returns the elements of a List
+func (v Value) list() (*[]Value, error) {
+	if v.Kind != List {
+		return nil, fmt.Errorf("expected a list, got %v '%v'", v.Kind, v)
+	}
+	return v.l, nil
+}
+
+// mapping returns the entries of a Map
+func (v Value) mapping() (*map[string]Value, error) {
+	if v.Kind != Map {
+		return nil, fmt.Errorf("expected a map, got %v '%v'", v.Kind, v)
+	}
+	return v.m, nil
+}
+
+// keys returns the keys of a Map in sorted order
+func (v Value) keys() []string {
+	keys := make([]string, 0, len(*v.m))
+	for k := range *v.m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// index returns the element of the List `v` at `i`, or the value of the Map
+// `v` at the key `i`
+func (v Value) index(i Value) (Value, error) {
+	switch v.Kind {
+	case List:
+		n, err := position(i, len(*v.l))
+		if err != nil {
+			return Value{}, err
+		}
+		return (*v.l)[n], nil
+	case Map:
+		k, err := key(i)
+		if err != nil {
+			return Value{}, err
+		}
+		val, ok := (*v.m)[k]
+		if !ok {
+			return Value{}, fmt.Errorf("no key %q in the map", k)
+		}
+		return val, nil
+	}
+	return Value{}, fmt.Errorf("can only index a list or a map, not %v '%v'", v.Kind, v)
+}
+
+// setIndex sets the element of the List `v` at `i`, or the value of the Map
+// `v` at the key `i`, to `val`
+func (v Value) setIndex(i, val Value) error {
+	switch v.Kind {
+	case List:
+		n, err := position(i, len(*v.l))
+		if err != nil {
+			return err
+		}
+		(*v.l)[n] = val
+		return nil
+	case Map:
+		k, err := key(i)
+		if err != nil {
+			return err
+		}
+		(*v.m)[k] = val
+		return nil
+	}
+	return fmt.Errorf("can only index a list or a map, not %v '%v'", v.Kind, v)
+}
+
+// key checks that `k` can be a key of a map
+func key(k Value) (string, error) {
+	if k.Kind != String {
+		return "", fmt.Errorf("map keys have to be strings, not %v '%v'", k.Kind, k)
+	}
+	return k.s, nil
+}
+
+// position checks that `i` is an Int index into a list of length `length`
+func position(i Value, length int) (int, error) {
+	if i.Kind != Int {
+		return 0, fmt.Errorf("index has to be an int, not %v '%v'", i.Kind, i)
+	}
+	if i.i < 0 || i.i >= int64(length) {
+		return 0, fmt.Errorf("index %v out of range for a list of length %v", i.i, length)
+	}
+	return int(i.i), nil
+}
+
+// number returns the value as a number for arithmetic. Bools count as 1 and
+// 0, and nil counts as 0.
+func (v Value) number() (Value, error) {
+	switch v.Kind {
+	case Int, Float:
+		return v, nil
+	case Bool, Nil:
+		return IntValue(v.i), nil
+	}
+	return Value{}, fmt.Errorf("expected a number, got %v '%v'", v.Kind, v)
+}
+
+// parseNumber parses a number literal, which is an Int unless it has a
+// fractional part or an exponent
+func parseNumber(s string) (Value, error) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return IntValue(i), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Value{}, fmt.Errorf("bad number '%v'", s)
+	}
+	return FloatValue(f), nil
+}
+
+// arithmetic applies the operator `op` to `left` and `right`. Adding a string
+// to anything joins them together. Otherwise both sides have to be numbers:
+// the result is an Int if they're both Ints, except for `/` which always
+// makes a Float.
+func arithmetic(op string, left, right Value) (Value, error) {
+	if op == "+" && (left.Kind == String || right.Kind == String) {
+		return StringValue(left.String() + right.String()), nil
+	}
+	l, err := left.number()
+	if err != nil {
+		return Value{}, fmt.Errorf("'%v' %v", op, err)
+	}
+	r, err := right.number()
+	if err != nil {
+		return Value{}, fmt.Errorf("'%v' %v", op, err)
+	}
+	if l.Kind == Int && r.Kind == Int && op != "/" {
+		switch op {
+		case "+":
+			return IntValue(l.i + r.i), nil
+		case "-":
+			return IntValue(l.i - r.i), nil
+		case "*":
+			return IntValue(l.i * r.i), nil
+		case "%":
+			if r.i == 0 {
+				return Value{}, fmt.Errorf("modulo by zero")
+			}
+			return IntValue(l.i % r.i), nil
+		}
+	}
+	switch op {
+	case "+":
+		return FloatValue(l.Float() + r.Float()), nil
+	case "-":
+		return FloatValue(l.Float() - r.Float()), nil
+	case "*":
+		return FloatValue(l.Float() * r.Float()), nil
+	case "/":
+		return FloatValue(l.Float() / r.Float()), nil
+	case "%":
+		return FloatValue(math.Mod(l.Float(), r.Float())), nil
+	}
+	return Value{}, fmt.Errorf("unknown operator '%v'", op)
+}
+
+// Operate applies the operator `op` to `operands` the way running a program
+// would. `-`, `!` and `not` take one operand, and the others take two.
+func Operate(op string, operands ...Value) (Value, error) {
+	switch {
+	case len(operands) == 1 && op == "-":
+		return negate(operands[0])
+	case len(operands) == 1 && (op == "!" || op == "not"):
+		return BoolValue(!operands[0].Bool()), nil
+	case len(operands) != 2:
+		return Value{}, fmt.Errorf("'%v' can't take %v operands", op, len(operands))
+	}
+	left, right := operands[0], operands[1]
+	switch op {
+	case "&":
+		return BoolValue(left.Bool() && right.Bool()), nil
+	case "|":
+		return BoolValue(left.Bool() || right.Bool()), nil
+	case "==", "!=", "<", ">", "<=", ">=":
+		return compare(op, left, right)
+	}
+	return arithmetic(op, left, right)
+}
+
+// negate returns the number `v` with its sign flipped
+func negate(v Value) (Value, error) {
+	n, err := v.number()
+	if err != nil {
+		return Value{}, fmt.Errorf("'-' %v", err)
+	}
+	if n.Kind == Int {
+		return IntValue(-n.i), nil
+	}
+	return FloatValue(-n.f), nil
+}
+
+// compare applies the comparison `op` to `left` and `right`. Numbers compare
+// by value, with bools counting as 1 and 0, and strings compare in
+// lexicographic order. Other values can't be ordered, and are only equal to
+// values of the same kind holding the same things.
+func compare(op string, left, right Value) (Value, error) {
+	switch op {
+	case "==":
+		return BoolValue(equal(left, right)), nil
+	case "!=":
+		return BoolValue(!equal(left, right)), nil
+	}
+	var less, same, greater bool
+	l, lerr := left.number()
+	r, rerr := right.number()
+	switch {
+	case left.Kind == String && right.Kind == String:
+		less, same, greater = left.s < right.s, left.s == right.s, left.s > right.s
+	case lerr != nil || rerr != nil:
+		return Value{}, fmt.Errorf("'%v' can't compare %v %v with %v %v", op, left.Kind, left.repr(), right.Kind, right.repr())
+	case l.Kind == Int && r.Kind == Int:
+		less, same, greater = l.i < r.i, l.i == r.i, l.i > r.i
+	default:
+		less, same, greater = l.Float() < r.Float(), l.Float() == r.Float(), l.Float() > r.Float()
+	}
+	switch op {
+	case "<":
+		return BoolValue(less), nil
+	case "<=":
+		return BoolValue(less || same), nil
+	case ">":
+		return BoolValue(greater), nil
+	case ">=":
+		return BoolValue(greater || same), nil
+	}
+	return Value{}, fmt.Errorf("unknown comparison '%v'", op)
+}
+
+// equal returns whether `left` and `right` are equal
+func equal(left, right Value) bool {
+	return equalIn(left, right, nil)
+}
+
+// pair is a pair of lists or maps being compared
+type pair struct {
+	left, right interface{}
+}
+
+// equalIn returns whether `left` and `right` are equal, given that the pairs
+// in `seen` are already being compared, so are equal unless something else
+// in them isn't
+func equalIn(left, right Value, seen map[pair]bool) bool {
+	if isNumber(left) && isNumber(right) {
+		if left.Kind == Float || right.Kind == Float {
+			return left.Float() == right.Float()
+		}
+		return left.i == right.i
+	}
+	if left.Kind != right.Kind {
+		return false
+	}
+	switch left.Kind {
+	case String:
+		return left.s == right.s
+	case List:
+		if len(*left.l) != len(*right.l) {
+			return false
+		}
+		p := pair{left.l, right.l}
+		if seen[p] {
+			return true
+		}
+		if seen == nil {
+			seen = make(map[pair]bool)
+		}
+		seen[p] = true
+		for i := range *left.l {
+			if !equalIn((*left.l)[i], (*right.l)[i], seen) {
+				return false
+			}
+		}
+		return true
+	case Map:
+		if len(*left.m) != len(*right.m) {
+			return false
+		}
+		p := pair{left.m, right.m}
+		if seen[p] {
+			return true
+		}
+		if seen == nil {
+			seen = make(map[pair]bool)
+		}
+		seen[p] = true
+		for k, l := range *left.m {
+			r, ok := (*right.m)[k]
+			if !ok || !equalIn(l, r, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return true // both nil
+}
+
+// isNumber returns whether `v` is a number, or a bool which can be used as one
+func isNumber(v Value) bool {
+	return v.Kind == Int || v.Kind == Float || v.Kind == Bool
+}
@@ -0,0 +1,255 @@
+package simpl
+
+import "fmt"
+
+// vmFrame holds the state of a chunk of code being run: the lines of the
+// program, or a function call
+type vmFrame struct {
+	c      *chunk
+	fn     *function // nil for the lines of the program
+	pc     int       // the index of the next instruction to run
+	base   int       // the height of the stack when the function was called
+	locals []Value
+	set    []bool // whether each local variable has been set
+	temps  []Value
+}
+
+// values is the stack of values the bytecode works on
+type values []Value
+
+func (s *values) push(v Value) {
+	*s = append(*s, v)
+}
+
+func (s *values) pop() Value {
+	v := (*s)[len(*s)-1]
+	*s = (*s)[:len(*s)-1]
+	return v
+}
+
+// execute runs the compiled lines of the program from the line `start` to the
+// end, returning the value of the last line run
+func (in *Interpreter) execute(start int) (Value, error) {
+	p := &in.prog
+	if start >= len(p.lines) {
+		return in.retval, nil
+	}
+	frames := []vmFrame{{c: &p.main, pc: p.lines[start], temps: make([]Value, p.main.temps)}}
+	f := &frames[0]
+	stack := values{}
+	for f.pc < len(f.c.code) {
+		ins := f.c.code[f.pc]
+		f.pc++
+		switch ins.op {
+		case opConst:
+			stack.push(p.consts[ins.a])
+		case opNil:
+			stack.push(Value{})
+		case opPop:
+			stack.pop()
+		case opResult:
+			in.retval = stack.pop()
+		case opGlobal:
+			stack.push(in.Vars[p.names[ins.a]])
+		case opSetGlobal:
+			if in.Vars == nil {
+				in.Vars = make(map[string]Value)
+			}
+			in.Vars[p.names[ins.a]] = stack.pop()
+		case opLocal:
+			// a local variable hides the global one once it's been set
+			if f.set[ins.a] {
+				stack.push(f.locals[ins.a])
+			} else {
+				stack.push(in.Vars[f.fn.locals[ins.a]])
+			}
+		case opSetLocal:
+			f.locals[ins.a] = stack.pop()
+			f.set[ins.a] = true
+		case opArith, opCompare:
+			right := stack.pop()
+			left := stack.pop()
+			var val Value
+			var err error
+			if ins.op == opArith {
+				val, err = arithmetic(arithOps[ins.a], left, right)
+			} else {
+				val, err = compare(compareOps[ins.a], left, right)
+			}
+			if err != nil {
+				return Value{}, f.error(err)
+			}
+			stack.push(val)
+		case opBool:
+			stack[len(stack)-1] = BoolValue(stack[len(stack)-1].Bool())
+		case opNot:
+			stack[len(stack)-1] = BoolValue(!stack[len(stack)-1].Bool())
+		case opNegate:
+			val, err := negate(stack[len(stack)-1])
+			if err != nil {
+				return Value{}, f.error(err)
+			}
+			stack[len(stack)-1] = val
+		case opJump:
+			f.pc = int(ins.a)
+		case opJumpIfFalse:
+			if !stack.pop().Bool() {
+				f.pc = int(ins.a)
+			}
+		case opJumpIfTrue:
+			if stack.pop().Bool() {
+				f.pc = int(ins.a)
+			}
+		case opList:
+			elems := make([]Value, ins.a)
+			copy(elems, stack[len(stack)-len(elems):])
+			stack = stack[:len(stack)-len(elems)]
+			stack.push(ListValue(elems))
+		case opKey:
+			if _, err := key(stack[len(stack)-2]); err != nil {
+				return Value{}, f.error(err)
+			}
+		case opMap:
+			entries := make(map[string]Value, ins.a)
+			top := len(stack) - 2*int(ins.a)
+			for i := top; i < len(stack); i += 2 {
+				entries[stack[i].s] = stack[i+1]
+			}
+			stack = stack[:top]
+			stack.push(MapValue(entries))
+		case opIndex:
+			i := stack.pop()
+			val, err := stack.pop().index(i)
+			if err != nil {
+				return Value{}, f.error(err)
+			}
+			stack.push(val)
+		case opSetIndex:
+			i := stack.pop()
+			list := stack.pop()
+			if err := list.setIndex(i, stack.pop()); err != nil {
+				return Value{}, f.error(err)
+			}
+		case opPrint:
+			fmt.Fprint(in.w, stack.pop())
+		case opGotoLabel:
+			i, ok := in.labels[p.names[ins.a]]
+			if !ok {
+				return Value{}, f.errorf("unknown label '%v'", p.names[ins.a])
+			}
+			f.pc = p.lines[i]
+		case opGoto:
+			line, err := stack.pop().number()
+			if err != nil {
+				return Value{}, f.errorf("'goto' %v", err)
+			}
+			if line.Int() < 1 || line.Int() > int64(len(p.lines)) {
+				return Value{}, f.errorf("cannot goto line %v, there are %v lines", line, len(p.lines))
+			}
+			f.pc = p.lines[line.Int()-1]
+		case opForNumber:
+			num, err := stack[len(stack)-1].number()
+			if err != nil {
+				return Value{}, f.errorf("'for' %v", err)
+			}
+			stack[len(stack)-1] = num
+		case opForPrep:
+			end := stack.pop()
+			start := stack.pop()
+			start, end, err := forBounds(start, end)
+			if err != nil {
+				return Value{}, f.errorf("'for' %v", err)
+			}
+			f.temps[ins.a], f.temps[ins.a+1] = start, end
+		case opForNext:
+			i, end := f.temps[ins.a], f.temps[ins.a+1]
+			if i.Kind == Int && i.i <= end.i || i.Kind == Float && i.f <= end.f {
+				stack.push(i)
+				f.pc++
+			}
+		case opForStep:
+			i := &f.temps[ins.a]
+			switch {
+			case i.Kind == Int && i.i == f.temps[ins.a+1].i:
+				// nil stops the loop, so that counting past the biggest int
+				// doesn't wrap around
+				*i = Value{}
+			case i.Kind == Int:
+				i.i++
+			default:
+				i.f++
+			}
+		case opCheckCall:
+			site := p.calls[ins.a]
+			if def, ok := in.funcs[site.name]; ok {
+				if params := len(def.right.args); site.args != params {
+					return Value{}, f.errorf("'%v' takes %v arguments, got %v", site.name, params, site.args)
+				}
+				if len(frames) > maxDepth {
+					return Value{}, f.errorf("calls are nested more than %v deep", maxDepth)
+				}
+				break
+			}
+			b, ok := in.builtin(site.name)
+			if !ok {
+				return Value{}, f.errorf("unknown function '%v'", site.name)
+			}
+			if err := b.arity(site.args); err != nil {
+				return Value{}, f.errorf("'%v' %v", site.name, err)
+			}
+		case opCall:
+			site := p.calls[ins.a]
+			base := len(stack) - site.args
+			if def, ok := in.funcs[site.name]; ok {
+				fn := p.function(def)
+				call := vmFrame{
+					c:      &fn.chunk,
+					fn:     fn,
+					base:   base,
+					locals: make([]Value, len(fn.locals)),
+					set:    make([]bool, len(fn.locals)),
+					temps:  make([]Value, fn.temps),
+				}
+				copy(call.locals, stack[base:])
+				for i := 0; i < fn.params; i++ {
+					call.set[i] = true
+				}
+				stack = stack[:base]
+				frames = append(frames, call)
+				f = &frames[len(frames)-1]
+				break
+			}
+			b, _ := in.builtin(site.name)
+			args := make([]Value, site.args)
+			copy(args, stack[base:])
+			stack = stack[:base]
+			val, err := b.fn(in, args)
+			if err != nil {
+				return Value{}, builtinError(f.c.pos[f.pc-1], site.name, err)
+			}
+			stack.push(val)
+		case opReturn:
+			val := stack.pop()
+			stack = stack[:f.base]
+			frames = frames[:len(frames)-1]
+			f = &frames[len(frames)-1]
+			stack.push(val)
+		case opFail:
+			return Value{}, f.error(p.errs[ins.a])
+		default:
+			return Value{}, f.errorf("unknown instruction %v", ins.op)
+		}
+	}
+	return in.retval, nil
+}
+
+// error makes `err` into a RuntimeError at the position of the instruction
+// being run
+func (f *vmFrame) error(err error) error {
+	return &RuntimeError{Pos: f.c.pos[f.pc-1], Err: err}
+}
+
+// errorf makes a RuntimeError at the position of the instruction being run
+func (f *vmFrame) errorf(format string, a ...interface{}) error {
+	return &RuntimeError{Pos: f.c.pos[f.pc-1], Err: fmt.Errorf(format, a...)}
+}
@@ -0,0 +1,212 @@
+package simpl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCompiledMatchesWalker runs programs both compiled and by walking their
+// trees, which should print the same things, end with the same value, and
+// fail with the same errors
+func TestCompiledMatchesWalker(t *testing.T) {
+	programs := map[string]string{
+		"locals": `x = "global"
+func f(a)
+print x + " " + a + " "
+x = "local"
+print x + " "
+end
+f("arg")
+print x`,
+		"recursion": `func fib(n)
+if n < 2
+return n
+end
+return fib(n - 1) + fib(n - 2)
+end
+print fib(15)`,
+		"float for": `for i = 0.5 to 3
+print i + " "
+end`,
+		"for in a function": `func count(n)
+total = 0
+for i = 1 to n
+total = total + i
+end
+return total
+end
+print count(10) + count(3)`,
+		"for keeps counting": `for i = 1 to 3
+print i
+i = 10
+end`,
+		"goto out of loops": `i = 0
+loop:
+i = i + 1
+while true
+if i % 2 == 0 goto loop
+for j = 1 to 3
+if i > 5 goto done
+end
+goto loop
+end
+done: print "done " + i`,
+		"goto a number": `i = 0
+i = i + 1
+if i < 5 goto 1 + 1
+i`,
+		"short circuit": `func f(x)
+print x
+return x
+end
+f(false) & f(true)
+f(1) | f(2)
+f(0) | f("")`,
+		"lists and maps": `xs = [1, [2, 3], {"a": 4}]
+xs[1][0] = 5
+m = {"k": xs, "n": nil}
+m["k"][2]["b"] = -xs[0]
+print m
+print len(keys(m))
+!has(m, "z")`,
+		"builtins": `print format("{} {}", upper("a"), max(3, 1, 2))
+print join(split("a,b,c", ","), "-")`,
+		"return from a loop": `func find(xs, x)
+for i = 0 to len(xs) - 1
+if xs[i] == x return i
+end
+return -1
+end
+print find([3, 4, 5], 5) + " " + find([], 1)`,
+		"bare return": `func f()
+return
+end
+f()`,
+		"label value": `l: 3 * 2`,
+		"error in a function": `func f(x)
+return x + [1]
+end
+print "before"
+f(1)
+print "after"`,
+		"error in arguments": `func f(a, b)
+end
+f(print("x"), 1 - "a")`,
+		"wrong arity": `func f(a)
+end
+f(print(1), 2)`,
+		"unknown function": `print 1
+g(print(2))`,
+		"bad goto": `goto "x"`,
+		"bad for": `for i = 1 to [1]
+end`,
+		"deep recursion": `func f(n)
+return f(n + 1)
+end
+f(0)`,
+		"exit": `print 1
+exit(3)
+print 2`,
+		"for up to the biggest int": `for i = 9223372036854775806 to 9223372036854775807
+print i + " "
+end`,
+		"for up to a huge float": `print 1
+for i = 0.5 to 1e20
+end`,
+		"map keys": `m = {"a": print("x"), 1: 2}`,
+		"cycles": `xs = [1]
+append(xs, xs)
+m = {"xs": xs}
+m["m"] = m
+print m
+xs == [1, xs]`,
+	}
+	examples, err := filepath.Glob("../example/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, example := range examples {
+		b, err := os.ReadFile(example)
+		if err != nil {
+			t.Fatal(err)
+		}
+		programs[example] = string(b)
+	}
+	// these are run a piece at a time, the way the REPL runs them
+	sessions := map[string][]string{
+		"label defined later": {
+			"x = 0\nx = x + 1\nif x > 1 goto done\nprint x",
+			"done: print \" done\"\nif x < 2 goto 2",
+		},
+		"function defined later": {
+			"func f()\nreturn 1\nend\nx = f()",
+			"func f()\nreturn 2\nend\nprint x + f()",
+		},
+		"unknown label": {
+			"print 1\ngoto nowhere",
+			"print 2",
+		},
+		"error in a piece": {
+			"x = 1\nprint y[0]\nx = 2",
+			"print x",
+		},
+	}
+	for name, input := range programs {
+		sessions[name] = []string{input}
+	}
+	for name, inputs := range sessions {
+		t.Run(name, func(t *testing.T) {
+			compiled := runBoth(t, inputs)
+			walked := runBoth(t, inputs, Walk())
+			if compiled != walked {
+				t.Errorf("compiled:\n%v\nwalked:\n%v", compiled, walked)
+			}
+		})
+	}
+}
+
+// TestCompilesMissingOperands checks that trees with missing operands, which
+// the parser shouldn't make, don't crash the compiled code
+func TestCompilesMissingOperands(t *testing.T) {
+	lines := []*Node{
+		{val: Token{Class: Unary, Repr: "-"}},
+		{val: Token{Class: Operator, Repr: "+"}, left: &Node{val: Token{Class: Num, Repr: "1"}}},
+	}
+	for _, opts := range [][]Option{nil, {Walk()}} {
+		i := NewInterpreter(&lines, io.Discard, opts...)
+		val, err := i.Interpret()
+		if err != nil || val != IntValue(1) {
+			t.Errorf("expected 1, got %v, %v", val, err)
+		}
+	}
+}
+
+// runBoth runs each of `inputs` in turn with `opts`, like lines typed into
+// the REPL, and describes what they printed, the values they ended with, and
+// their errors
+func runBoth(t *testing.T, inputs []string, opts ...Option) string {
+	t.Helper()
+	p := Parser{Incremental: len(inputs) > 1}
+	out := strings.Builder{}
+	i := NewInterpreter(&p.Lines, &out, opts...)
+	i.Seed(1)
+	results := ""
+	for _, input := range inputs {
+		l := Lexer{In: strings.NewReader(input), File: "test"}
+		tkns, errs := l.Lex()
+		if len(errs) > 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+		p.Tokens = tkns
+		if errs := p.Parse(); len(errs) > 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+		val, err := i.Interpret()
+		results += fmt.Sprintf("%v %v\n%v\n", val.Kind, val.repr(), err)
+	}
+	return fmt.Sprintf("%q\n%v", out.String(), results)
+}
@@ -1,26 +1,75 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"simple/optimize"
 	"simple/simpl"
+	"strings"
 )
 
 var usage = func() {
 	fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
-	fmt.Fprintf(flag.CommandLine.Output(), "%s <input file> \n", os.Args[0])
+	fmt.Fprintf(flag.CommandLine.Output(), "%s [-i] [-O] [-dump-ast format] [-tokens [-tokens-format format]] [-walk] [-seed n] [-allow-read dir] [-allow-write dir] [input file [args...]] \n", os.Args[0])
 	flag.PrintDefaults()
 }
 
+var interactive = flag.Bool("i", false, "start an interactive session (after running the input file, if given)")
+var optimized = flag.Bool("O", false, "optimize the program before running it")
+var dumpAST = flag.String("dump-ast", "", "print the syntax trees of the program (after optimizing it, with -O) as `format` text, json, or dot, instead of running it")
+var tokens = flag.Bool("tokens", false, "print the tokens of the program instead of running it")
+var tokensFormat = flag.String("tokens-format", "table", "print the tokens from -tokens as `format` table, or json with a line for each token")
+var walk = flag.Bool("walk", false, "run the program by walking its syntax trees instead of compiling it to bytecode")
+var seed = flag.Int64("seed", 0, "seed random() with `n`, so it gives the same numbers every run")
+var allowRead, allowWrite dirs
+
+func init() {
+	flag.Var(&allowRead, "allow-read", "let the program read files in `dir` (can be given more than once)")
+	flag.Var(&allowWrite, "allow-write", "let the program write files in `dir` (can be given more than once)")
+}
+
+// dirs is a flag that can be given more than once, collecting directories
+type dirs []string
+
+func (d *dirs) String() string {
+	return strings.Join(*d, ",")
+}
+
+func (d *dirs) Set(dir string) error {
+	*d = append(*d, dir)
+	return nil
+}
+
 func main() {
+	flag.Usage = usage
 	flag.Parse()
 
+	// the program and the interactive session share stdin
+	stdin := bufio.NewReader(os.Stdin)
+	p := simpl.Parser{}
+	var args []string
+	if flag.NArg() > 1 {
+		args = flag.Args()[1:]
+	}
+	opts := []simpl.Option{simpl.Input(stdin),
+		simpl.AllowRead(allowRead...), simpl.AllowWrite(allowWrite...),
+		simpl.Args(args...), simpl.Env(os.LookupEnv)}
+	if *walk {
+		opts = append(opts, simpl.Walk())
+	}
+	i := simpl.NewInterpreter(&p.Lines, os.Stdout, opts...)
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			i.Seed(*seed)
+		}
+	})
+
 	in := flag.Arg(0)
 	if in == "" {
-		usage()
-		return
+		os.Exit(repl(&p, &i, stdin, os.Stdout))
 	}
 
 	infile, err := os.Open(in)
@@ -37,24 +86,80 @@ func main() {
 		log.Fatalf("'%v' is a directory\n", in)
 	}
 
-	l := simpl.Lexer{In: infile}
-	tokens, errors := l.Lex()
+	l := simpl.Lexer{In: infile, File: in}
+	tkns, errors := l.Lex()
+	if *tokens {
+		if err := dumpTokens(*tokensFormat, tkns); err != nil {
+			log.Fatalf("could not print the tokens of '%v': %v", in, err)
+		}
+	}
 	if len(errors) > 0 {
-		for _, err := range errors {
-			fmt.Println("ERROR:", err)
+		fatalErrors(errors, "lexing", in)
+	}
+	if *tokens {
+		return
+	}
+
+	p.Tokens = tkns
+	if errors := p.Parse(); len(errors) > 0 {
+		fatalErrors(errors, "parsing", in)
+	}
+	if *optimized {
+		p.Lines = optimize.Lines(p.Lines)
+	}
+	if *dumpAST != "" {
+		if err := dump(*dumpAST, p.Lines); err != nil {
+			log.Fatalf("could not dump '%v': %v", in, err)
 		}
-		verb := "was"
-		e := "error"
-		if len(errors) > 1 {
-			verb = "were"
-			e += "s"
+		return
+	}
+
+	if _, err := i.Interpret(); err != nil {
+		if exit, ok := err.(*simpl.ExitError); ok {
+			os.Exit(exit.Code)
 		}
-		log.Fatalf("there %s %v %s lexing '%v'", verb, len(errors), e, in)
+		log.Fatalf("ERROR: %v", err)
 	}
 
-	p := simpl.Parser{Tokens: tokens}
-	p.Parse()
+	if *interactive {
+		os.Exit(repl(&p, &i, stdin, os.Stdout))
+	}
+}
 
-	i := simpl.NewInterpreter(&p.Lines, os.Stdout)
-	i.Interpret()
+// dump writes the syntax trees `lines` to stdout in `format`
+func dump(format string, lines []*simpl.Node) error {
+	switch format {
+	case "text":
+		return simpl.DumpText(os.Stdout, lines)
+	case "json":
+		return simpl.DumpJSON(os.Stdout, lines)
+	case "dot":
+		return simpl.DumpDot(os.Stdout, lines)
+	}
+	return fmt.Errorf("unknown format '%v', expected text, json, or dot", format)
+}
+
+// dumpTokens writes `tokens` to stdout in `format`
+func dumpTokens(format string, tokens []simpl.Token) error {
+	switch format {
+	case "table":
+		return simpl.DumpTokens(os.Stdout, tokens)
+	case "json":
+		return simpl.DumpTokensJSON(os.Stdout, tokens)
+	}
+	return fmt.Errorf("unknown format '%v', expected table or json", format)
+}
+
+// fatalErrors prints `errors` and exits
+func fatalErrors(errors []error, doing, in string) {
+	for _, err := range errors {
+		fmt.Println("ERROR:", err)
+	}
+	verb := "was"
+	e := "error"
+	if len(errors) > 1 {
+		verb = "were"
+		e += "s"
+	}
+	log.Fatalf("there %s %v %s %s '%v'", verb, len(errors), e, doing, in)
 }